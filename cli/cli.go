@@ -0,0 +1,501 @@
+// Package cli runs the actions of the GUI from the command line, so that they
+// work over ssh and in CI without a display.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RoomOfRequirement/qt_grpc/session"
+)
+
+// Exit codes of Run.
+const (
+	ExitOK     = 0
+	ExitFailed = 1 // the command failed
+	ExitUsage  = 2 // bad command line
+	ExitSLO    = 3 // a load test assertion did not hold
+)
+
+type command struct {
+	usage string
+	run   func(c *cmd, args []string) error
+}
+
+var commands = map[string]command{
+	"list":     {"list [flags] [service]\n\tlists the services, or the methods of service", list},
+	"describe": {"describe [flags] [method]\n\tdescribes every service, or method and the types it uses", describe},
+	"call":     {"call [flags] method\n\tcalls method with the messages given by -d", call},
+	"loadtest": {"loadtest [flags] method\n\tload tests method with ghz, -assert sets the exit code", loadtest},
+}
+
+// IsCommand tells whether name is a subcommand, the GUI starts otherwise.
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok || name == "help" || name == "-h" || name == "--help"
+}
+
+// errUsage has already been reported by the flag set.
+var errUsage = errors.New("usage")
+
+// errSLO is returned once the failed assertions are printed.
+var errSLO = errors.New("Load test assertions failed")
+
+// cmd is the state shared by every subcommand.
+type cmd struct {
+	flags  *flag.FlagSet
+	stdout io.Writer
+	stderr io.Writer
+	conn   connFlags
+	format string
+}
+
+// Run runs the subcommand args[0] and returns the exit code of the process.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || !IsCommand(args[0]) {
+		usage(stderr)
+		return ExitUsage
+	}
+	if args[0] == "help" || strings.HasPrefix(args[0], "-") {
+		usage(stdout)
+		return ExitOK
+	}
+	c := &cmd{
+		flags:  flag.NewFlagSet(args[0], flag.ContinueOnError),
+		stdout: stdout,
+		stderr: stderr,
+	}
+	c.flags.SetOutput(stderr)
+	c.conn.register(c.flags)
+
+	err := commands[args[0]].run(c, args[1:])
+	switch {
+	case err == nil:
+		return ExitOK
+	case err == errUsage || err == flag.ErrHelp:
+		return ExitUsage
+	case err == errSLO:
+		fmt.Fprintln(stderr, err)
+		return ExitSLO
+	default:
+		fmt.Fprintln(stderr, err)
+		return ExitFailed
+	}
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "usage: qt_grpc [command [flags] [args]]\n\nwithout a command the GUI is started, commands are:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nrun qt_grpc command -h for its flags")
+}
+
+// parse parses the flags of the subcommand and checks the number of
+// positional arguments.
+func (c *cmd) parse(args []string, min, max int) error {
+	if err := c.flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if n := c.flags.NArg(); n < min || n > max {
+		fmt.Fprintf(c.stderr, "%s: expected %d to %d arguments, got %d\n", c.flags.Name(), min, max, n)
+		c.flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func (c *cmd) formatFlag() {
+	c.flags.StringVar(&c.format, "format", "text", "output format, text or json")
+}
+
+func (c *cmd) session() (*session.Session, error) {
+	cfg, err := c.conn.config()
+	if err != nil {
+		return nil, err
+	}
+	return session.New(cfg), nil
+}
+
+// context is cancelled on interrupt, which stops load tests with a partial
+// report.
+func (c *cmd) context() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+func (c *cmd) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type methodJSON struct {
+	Name            string `json:"name"`
+	InputType       string `json:"input_type"`
+	OutputType      string `json:"output_type"`
+	ClientStreaming bool   `json:"client_streaming"`
+	ServerStreaming bool   `json:"server_streaming"`
+}
+
+func list(c *cmd, args []string) error {
+	c.formatFlag()
+	if err := c.parse(args, 0, 1); err != nil {
+		return err
+	}
+	s, err := c.session()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	if c.flags.NArg() == 0 {
+		svcs, err := s.ListServices(ctx)
+		if err != nil {
+			return err
+		}
+		names := make([]string, len(svcs))
+		for i, svc := range svcs {
+			names[i] = svc.Name
+		}
+		if c.format == "json" {
+			return c.printJSON(names)
+		}
+		for _, name := range names {
+			fmt.Fprintln(c.stdout, name)
+		}
+		return nil
+	}
+
+	methods, err := s.ListMethods(ctx, c.flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	res := make([]methodJSON, len(methods))
+	for i, m := range methods {
+		res[i] = methodJSON{m.Name, m.InputType, m.OutputType, m.ClientStreaming, m.ServerStreaming}
+	}
+	if c.format == "json" {
+		return c.printJSON(res)
+	}
+	for _, m := range methods {
+		fmt.Fprintf(c.stdout, "%s (%s) returns (%s) %s\n", m.Name, m.InputType, m.OutputType, m.Kind())
+	}
+	return nil
+}
+
+func describe(c *cmd, args []string) error {
+	c.formatFlag()
+	if err := c.parse(args, 0, 1); err != nil {
+		return err
+	}
+	s, err := c.session()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	var symbols []session.Symbol
+	if c.flags.NArg() == 0 {
+		symbols, err = s.Describe(ctx)
+	} else {
+		symbols, err = s.MethodDetails(ctx, c.flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	if c.format == "json" {
+		type symbolJSON struct {
+			Name  string `json:"name"`
+			Type  string `json:"type"`
+			Proto string `json:"proto"`
+		}
+		res := make([]symbolJSON, len(symbols))
+		for i, sym := range symbols {
+			res[i] = symbolJSON{sym.Name, sym.ElementType, sym.Text}
+		}
+		return c.printJSON(res)
+	}
+	if len(symbols) != 0 {
+		if types := symbols[0].Types(); types != nil {
+			for _, n := range types {
+				fmt.Fprint(c.stdout, n.String())
+			}
+			fmt.Fprintln(c.stdout)
+		}
+	}
+	for _, sym := range symbols {
+		fmt.Fprint(c.stdout, sym.String())
+	}
+	return nil
+}
+
+func call(c *cmd, args []string) error {
+	c.formatFlag()
+	data := c.flags.String("d", "", "request message(s) in json, @file reads a file and @- stdin")
+	timeout := c.flags.Duration("max-time", 0, "bound of the whole call, 0 is unbounded")
+	if err := c.parse(args, 1, 1); err != nil {
+		return err
+	}
+	msg, err := readData(*data)
+	if err != nil {
+		return err
+	}
+	s, err := c.session()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+	if *timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	res, err := s.Invoke(ctx, c.flags.Arg(0), msg)
+	if err != nil {
+		return err
+	}
+	if c.format == "json" {
+		out := struct {
+			Responses []json.RawMessage   `json:"responses"`
+			Headers   map[string][]string `json:"headers"`
+			Trailers  map[string][]string `json:"trailers"`
+			Code      string              `json:"code"`
+			Message   string              `json:"message"`
+			Details   []json.RawMessage   `json:"details,omitempty"`
+		}{
+			Headers:  res.Headers,
+			Trailers: res.Trailers,
+			Code:     res.Status.Code().String(),
+			Message:  res.Status.Message(),
+		}
+		dec := json.NewDecoder(strings.NewReader(res.Output))
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err == io.EOF {
+				break
+			} else if err != nil {
+				return fmt.Errorf("Failed to parse response due to: %s", err.Error())
+			}
+			out.Responses = append(out.Responses, raw)
+		}
+		for _, d := range res.Details {
+			out.Details = append(out.Details, json.RawMessage(d.JSON))
+		}
+		if err := c.printJSON(out); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprint(c.stdout, res.Output)
+		if res.Status.Code() != 0 {
+			fmt.Fprint(c.stderr, res.StatusText())
+		}
+	}
+	if res.Status.Code() != 0 {
+		return fmt.Errorf("Call failed with status %s", res.Status.Code())
+	}
+	return nil
+}
+
+func loadtest(c *cmd, args []string) error {
+	opts := session.DefaultLoadTestOptions()
+	var (
+		data        = c.flags.String("d", "", "request message or json array of messages, @file reads a file and @- stdin")
+		jsonLines   = c.flags.String("data-lines", "", "json lines file of request messages")
+		binaryFile  = c.flags.String("data-bin", "", "binary protobuf file of request messages")
+		concurrency = c.flags.Uint("c", opts.Concurrency, "concurrent workers")
+		total       = c.flags.Uint("n", opts.TotalRequests, "total requests")
+		duration    = c.flags.Duration("z", opts.Duration, "max duration, 0 is unbounded")
+		rps         = c.flags.Uint("rps", 0, "rate limit in requests per second, 0 is unlimited")
+		connections = c.flags.Uint("connections", opts.Connections, "connections shared by the workers")
+		reqTimeout  = c.flags.Duration("timeout", opts.Timeout, "timeout of every request")
+		name        = c.flags.String("name", "", "name of the run")
+		format      = c.flags.String("format", "summary", "report format: "+strings.Join(session.ReportFormats, ", "))
+		out         = c.flags.String("out", "", "write the report to this file instead of stdout")
+		asserts     multiFlag
+	)
+	c.flags.Var(&asserts, "assert", "threshold the report must meet, e.g. \"p99 < 200ms\", repeatable")
+	if err := c.parse(args, 1, 1); err != nil {
+		return err
+	}
+	assertions, err := session.ParseAssertions(asserts)
+	if err != nil {
+		return err
+	}
+
+	payload := session.Payload{Kind: session.PayloadJSON}
+	switch {
+	case *jsonLines != "":
+		payload = session.Payload{Kind: session.PayloadJSONLines, File: *jsonLines}
+	case *binaryFile != "":
+		payload = session.Payload{Kind: session.PayloadBinary, File: *binaryFile}
+	default:
+		if payload.Text, err = readData(*data); err != nil {
+			return err
+		}
+	}
+	opts.Name = *name
+	opts.Concurrency = *concurrency
+	opts.TotalRequests = *total
+	opts.Duration = *duration
+	opts.RPS = *rps
+	opts.Connections = *connections
+	opts.Timeout = *reqTimeout
+
+	s, err := c.session()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	ctx, cancel := c.context()
+	defer cancel()
+
+	report, err := s.LoadTest(ctx, c.flags.Arg(0), payload, opts, nil)
+	if err != nil {
+		return err
+	}
+	if *out != "" {
+		err = session.SaveReport(*out, report, *format)
+	} else {
+		err = session.WriteReport(c.stdout, report, *format)
+	}
+	if err != nil {
+		return err
+	}
+
+	results := session.Check(report, assertions)
+	for _, r := range results {
+		verdict := "pass"
+		if !r.Pass {
+			verdict = "FAIL"
+		}
+		fmt.Fprintf(c.stderr, "%s\t%s (actual %s)\n", verdict, r.Text, r.ActualText())
+	}
+	if !session.Passed(results) {
+		return errSLO
+	}
+	return nil
+}
+
+// readData resolves the @file and @- forms of -d.
+func readData(data string) (string, error) {
+	switch {
+	case data == "@-":
+		b, err := ioutil.ReadAll(os.Stdin)
+		return string(b), err
+	case strings.HasPrefix(data, "@"):
+		b, err := ioutil.ReadFile(data[1:])
+		if err != nil {
+			return "", fmt.Errorf("Failed to read request data due to: %s", err.Error())
+		}
+		return string(b), nil
+	}
+	return data, nil
+}
+
+// connFlags are the connection settings of the config group of the GUI.
+type connFlags struct {
+	addr        string
+	plaintext   bool
+	serverName  string
+	authority   string
+	cacert      string
+	cert        string
+	key         string
+	insecure    bool
+	dialTimeout time.Duration
+	headers     multiFlag
+	protos      string
+	protosets   string
+	importPaths string
+}
+
+func (f *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.addr, "addr", "localhost:10000", "server address")
+	fs.BoolVar(&f.plaintext, "plaintext", false, "connect without TLS")
+	fs.StringVar(&f.serverName, "servername", "", "server name to verify the certificate against")
+	fs.StringVar(&f.authority, "authority", "", ":authority pseudo header")
+	fs.StringVar(&f.cacert, "cacert", "", "root CA file, the system pool when empty")
+	fs.StringVar(&f.cert, "cert", "", "client certificate file")
+	fs.StringVar(&f.key, "key", "", "client key file")
+	fs.BoolVar(&f.insecure, "insecure", false, "skip verification of the server certificate")
+	fs.DurationVar(&f.dialTimeout, "connect-timeout", session.DefaultDialTimeout, "dial timeout")
+	fs.Var(&f.headers, "H", "\"key: value\" metadata, base64 values for -bin keys, repeatable")
+	fs.StringVar(&f.protos, "proto", "", "comma separated .proto files, instead of reflection")
+	fs.StringVar(&f.protosets, "protoset", "", "comma separated protoset files, instead of reflection")
+	fs.StringVar(&f.importPaths, "import-path", "", "comma separated import paths of -proto")
+}
+
+func (f *connFlags) config() (session.Config, error) {
+	cfg := session.Config{
+		Address:     f.addr,
+		PlainText:   f.plaintext,
+		ServerName:  f.serverName,
+		Authority:   f.authority,
+		DialTimeout: f.dialTimeout,
+		CA: session.CA{
+			Insecure: f.insecure,
+			CACert:   f.cacert,
+			Cert:     f.cert,
+			Key:      f.key,
+		},
+	}
+	switch {
+	case f.protos != "" && f.protosets != "":
+		return cfg, errors.New("Use either -proto or -protoset")
+	case f.protos != "":
+		cfg.Schema = session.Schema{Kind: session.SchemaProtoFiles, Files: splitList(f.protos), ImportPaths: splitList(f.importPaths)}
+	case f.protosets != "":
+		cfg.Schema = session.Schema{Kind: session.SchemaProtosets, Files: splitList(f.protosets)}
+	}
+	for _, h := range f.headers {
+		pos := strings.Index(h, ":")
+		if pos < 0 {
+			return cfg, fmt.Errorf("Metadata %q is not in the \"key: value\" form", h)
+		}
+		cfg.Metadata = append(cfg.Metadata, session.Header{Key: strings.TrimSpace(h[:pos]), Value: strings.TrimSpace(h[pos+1:])})
+	}
+	return cfg, cfg.Metadata.Validate()
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, i := range strings.Split(s, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// multiFlag is a repeatable string flag.
+type multiFlag []string
+
+func (m *multiFlag) String() string {
+	return strings.Join(*m, ", ")
+}
+
+func (m *multiFlag) Set(v string) error {
+	*m = append(*m, v)
+	return nil
+}
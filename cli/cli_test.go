@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// testServer serves health and reflection on a random port.
+func testServer(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestIsCommand(t *testing.T) {
+	for name, want := range map[string]bool{
+		"list": true, "describe": true, "call": true, "loadtest": true,
+		"help": true, "-h": true, "--help": true,
+		"": false, "-plaintext": false, "gui": false,
+	} {
+		if got := IsCommand(name); got != want {
+			t.Errorf("IsCommand(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	addr := testServer(t)
+	conn := []string{"-plaintext", "-addr", addr, "-connect-timeout", "1s"}
+	with := func(command string, args ...string) []string {
+		return append(append([]string{command}, conn...), args...)
+	}
+	const check = "grpc.health.v1.Health/Check"
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "no command", want: ExitUsage, wantStderr: "usage:"},
+		{name: "unknown command", args: []string{"gui"}, want: ExitUsage, wantStderr: "usage:"},
+		{name: "help", args: []string{"help"}, want: ExitOK, wantStdout: "usage:"},
+		{name: "command help", args: []string{"list", "-h"}, want: ExitUsage, wantStderr: "-addr"},
+		{name: "unknown flag", args: with("list", "-nope"), want: ExitUsage, wantStderr: "-nope"},
+		{name: "too many arguments", args: with("list", "a", "b"), want: ExitUsage, wantStderr: "expected 0 to 1 arguments"},
+		{name: "missing method", args: with("call"), want: ExitUsage, wantStderr: "expected 1 to 1 arguments"},
+		{name: "list services", args: with("list"), want: ExitOK, wantStdout: "grpc.health.v1.Health\n"},
+		{name: "list services as json", args: with("list", "-format", "json"), want: ExitOK, wantStdout: `"grpc.health.v1.Health"`},
+		{name: "list methods", args: with("list", "grpc.health.v1.Health"), want: ExitOK, wantStdout: "Check (grpc.health.v1.HealthCheckRequest)"},
+		{name: "list unknown service", args: with("list", "test.Missing"), want: ExitFailed, wantStderr: "test.Missing"},
+		{name: "describe method", args: with("describe", check), want: ExitOK, wantStdout: "HealthCheckRequest"},
+		{name: "unreachable server", args: []string{"list", "-plaintext", "-addr", "127.0.0.1:1", "-connect-timeout", "100ms"}, want: ExitFailed, wantStderr: "Failed to dial"},
+		{name: "both schema flags", args: with("list", "-proto", "a.proto", "-protoset", "a.protoset"), want: ExitFailed, wantStderr: "Use either"},
+		{name: "invalid header", args: with("list", "-H", "nocolon"), want: ExitFailed, wantStderr: "key: value"},
+		{name: "call", args: with("call", "-d", "{}", check), want: ExitOK, wantStdout: `"status": "SERVING"`},
+		{name: "call as json", args: with("call", "-format", "json", "-d", "{}", check), want: ExitOK, wantStdout: `"code": "OK"`},
+		{name: "call failing", args: with("call", "-d", `{"service":"missing"}`, check), want: ExitFailed, wantStderr: "NotFound"},
+		{name: "call invalid message", args: with("call", "-d", `{"nope":1}`, check), want: ExitFailed},
+		{
+			name:       "load test assertions hold",
+			args:       with("loadtest", "-n", "4", "-c", "1", "-d", "{}", "-assert", "errors == 0", "-assert", "count == 4", check),
+			want:       ExitOK,
+			wantStdout: "Summary",
+			wantStderr: "pass\tcount == 4",
+		},
+		{
+			name:       "load test assertion fails",
+			args:       with("loadtest", "-n", "4", "-c", "1", "-d", "{}", "-assert", "count > 4", check),
+			want:       ExitSLO,
+			wantStderr: "FAIL\tcount > 4",
+		},
+		{
+			name:       "load test invalid assertion",
+			args:       with("loadtest", "-n", "4", "-d", "{}", "-assert", "p42 < 1ms", check),
+			want:       ExitFailed,
+			wantStderr: "unknown percentile",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := Run(tt.args, &stdout, &stderr); got != tt.want {
+				t.Errorf("Run(%q) = %d, want %d\nstdout: %s\nstderr: %s", tt.args, got, tt.want, stdout.String(), stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) {
+				t.Errorf("stdout = %q, want it to contain %q", stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/RoomOfRequirement/qt_grpc/session"
+	"github.com/therecipe/qt/widgets"
+)
+
+// historyView lists past load test runs and compares two of them.
+type historyView struct {
+	*widgets.QWidget
+
+	runs []session.Run
+	list *widgets.QTableWidget
+	diff *widgets.QTableWidget
+
+	// wired by the owner
+	showButton    *widgets.QPushButton
+	compareButton *widgets.QPushButton
+	removeButton  *widgets.QPushButton
+}
+
+func newHistoryView() *historyView {
+	v := &historyView{QWidget: widgets.NewQWidget(nil, 0)}
+	v.list = newReadOnlyTable("date", "name", "method", "target", "rps", "p99", "errors")
+	v.list.SetSelectionBehavior(widgets.QAbstractItemView__SelectRows)
+	v.list.SetSelectionMode(widgets.QAbstractItemView__ExtendedSelection)
+	v.diff = newReadOnlyTable("metric", "baseline", "run", "change")
+
+	v.showButton = widgets.NewQPushButton2("show", nil)
+	v.compareButton = widgets.NewQPushButton2("compare", nil)
+	v.compareButton.SetToolTip("select two runs, the older one is the baseline")
+	v.removeButton = widgets.NewQPushButton2("remove", nil)
+	buttonLayout := widgets.NewQVBoxLayout()
+	buttonLayout.AddWidget(v.showButton, 0, 0)
+	buttonLayout.AddWidget(v.compareButton, 0, 0)
+	buttonLayout.AddWidget(v.removeButton, 0, 0)
+	buttonLayout.AddStretch(1)
+
+	layout := widgets.NewQHBoxLayout()
+	layout.AddWidget(v.list, 3, 0)
+	layout.AddLayout(buttonLayout, 0)
+	layout.AddWidget(v.diff, 2, 0)
+	v.SetLayout(layout)
+	return v
+}
+
+func (v *historyView) SetRuns(runs []session.Run) {
+	v.runs = runs
+	rows := make([][]string, len(runs))
+	for i, run := range runs {
+		r := run.Report
+		p99 := ""
+		for _, l := range r.LatencyDistribution {
+			if l.Percentage == 99 {
+				p99 = l.Latency.String()
+			}
+		}
+		rows[i] = []string{
+			r.Date.Local().Format("2006-01-02 15:04:05"),
+			r.Name,
+			run.Method,
+			run.Target,
+			fmt.Sprintf("%.2f", r.Rps),
+			p99,
+			fmt.Sprintf("%.2f%%", session.ErrorRate(r)),
+		}
+	}
+	setRows(v.list, rows)
+	v.diff.SetRowCount(0)
+}
+
+// Selected returns the selected runs, oldest first.
+func (v *historyView) Selected() []session.Run {
+	rows := map[int]bool{}
+	for _, item := range v.list.SelectedItems() {
+		rows[item.Row()] = true
+	}
+	var idx []int
+	for row := range rows {
+		idx = append(idx, row)
+	}
+	// runs are listed newest first
+	sort.Sort(sort.Reverse(sort.IntSlice(idx)))
+	res := make([]session.Run, 0, len(idx))
+	for _, row := range idx {
+		res = append(res, v.runs[row])
+	}
+	return res
+}
+
+// Compare shows the deltas of run against baseline.
+func (v *historyView) Compare(baseline, run session.Run) {
+	deltas := session.Compare(baseline, run)
+	rows := make([][]string, len(deltas))
+	for i, d := range deltas {
+		change := fmt.Sprintf("%+.1f%%", d.Change()*100)
+		if d.A != d.B && d.Regression() {
+			change += " worse"
+		}
+		rows[i] = []string{d.Metric, formatDelta(d.A, d.Unit), formatDelta(d.B, d.Unit), change}
+	}
+	setRows(v.diff, rows)
+}
+
+func formatDelta(v float64, unit string) string {
+	if unit == "" {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f %s", v, unit)
+}
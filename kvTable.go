@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/therecipe/qt/widgets"
+)
+
+// kvTable is an editable key/value table with buttons to add and remove rows.
+type kvTable struct {
+	*widgets.QWidget
+
+	table *widgets.QTableWidget
+}
+
+func newKVTable(keyLabel, valueLabel string) *kvTable {
+	t := &kvTable{QWidget: widgets.NewQWidget(nil, 0)}
+	t.table = widgets.NewQTableWidget2(0, 2, nil)
+	t.table.SetHorizontalHeaderLabels([]string{keyLabel, valueLabel})
+	t.table.HorizontalHeader().SetStretchLastSection(true)
+
+	addButton := widgets.NewQPushButton2("+", nil)
+	removeButton := widgets.NewQPushButton2("-", nil)
+	buttonLayout := widgets.NewQVBoxLayout()
+	buttonLayout.AddWidget(addButton, 0, 0)
+	buttonLayout.AddWidget(removeButton, 0, 0)
+	buttonLayout.AddStretch(1)
+
+	layout := widgets.NewQHBoxLayout()
+	layout.AddWidget(t.table, 1, 0)
+	layout.AddLayout(buttonLayout, 0)
+	t.SetLayout(layout)
+
+	addButton.ConnectClicked(func(checked bool) {
+		t.addRow("", "")
+	})
+
+	removeButton.ConnectClicked(func(checked bool) {
+		if row := t.table.CurrentRow(); row >= 0 {
+			t.table.RemoveRow(row)
+		}
+	})
+
+	return t
+}
+
+func (t *kvTable) addRow(key, value string) {
+	row := t.table.RowCount()
+	t.table.InsertRow(row)
+	t.table.SetItem(row, 0, widgets.NewQTableWidgetItem2(key, 0))
+	t.table.SetItem(row, 1, widgets.NewQTableWidgetItem2(value, 0))
+}
+
+// Pairs returns the rows in order, skipping those without a key.
+func (t *kvTable) Pairs() [][2]string {
+	var res [][2]string
+	for row := 0; row < t.table.RowCount(); row++ {
+		key := t.table.Item(row, 0).Text()
+		if key == "" {
+			continue
+		}
+		res = append(res, [2]string{key, t.table.Item(row, 1).Text()})
+	}
+	return res
+}
+
+func (t *kvTable) SetPairs(pairs [][2]string) {
+	t.table.SetRowCount(0)
+	for _, p := range pairs {
+		t.addRow(p[0], p[1])
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RoomOfRequirement/qt_grpc/session"
+	"github.com/therecipe/qt/widgets"
+)
+
+// loadTestPanel edits the ghz options of a load test. Durations are entered
+// in the time.ParseDuration form, e.g. "1m30s".
+type loadTestPanel struct {
+	*widgets.QWidget
+
+	name          *widgets.QLineEdit
+	concurrency   *widgets.QLineEdit
+	connections   *widgets.QLineEdit
+	totalRequests *widgets.QLineEdit
+	duration      *widgets.QLineEdit
+	rps           *widgets.QLineEdit
+	timeout       *widgets.QLineEdit
+	keepalive     *widgets.QLineEdit
+
+	streamInterval     *widgets.QLineEdit
+	streamCallDuration *widgets.QLineEdit
+	streamCallCount    *widgets.QLineEdit
+	streamDynamic      *widgets.QCheckBox
+
+	tags *kvTable
+
+	// request messages, the message editor is used for json
+	payloadKind   *widgets.QComboBox
+	payloadFile   *widgets.QLineEdit
+	payloadBrowse *widgets.QPushButton
+	previewButton *widgets.QPushButton // wired by the owner
+
+	profileKind         *widgets.QComboBox
+	profileStart        *widgets.QLineEdit
+	profileEnd          *widgets.QLineEdit
+	profileStep         *widgets.QLineEdit
+	profileStepDuration *widgets.QLineEdit
+
+	assertions *widgets.QTextEdit
+}
+
+func newLoadTestPanel() *loadTestPanel {
+	p := &loadTestPanel{QWidget: widgets.NewQWidget(nil, 0)}
+	p.name = widgets.NewQLineEdit2("", nil)
+	p.concurrency = widgets.NewQLineEdit2("", nil)
+	p.connections = widgets.NewQLineEdit2("", nil)
+	p.totalRequests = widgets.NewQLineEdit2("", nil)
+	p.duration = widgets.NewQLineEdit2("", nil)
+	p.duration.SetPlaceholderText("unbounded")
+	p.rps = widgets.NewQLineEdit2("", nil)
+	p.rps.SetPlaceholderText("unlimited")
+	p.timeout = widgets.NewQLineEdit2("", nil)
+	p.keepalive = widgets.NewQLineEdit2("", nil)
+	p.keepalive.SetPlaceholderText("off")
+	p.streamInterval = widgets.NewQLineEdit2("", nil)
+	p.streamCallDuration = widgets.NewQLineEdit2("", nil)
+	p.streamCallCount = widgets.NewQLineEdit2("", nil)
+	p.streamDynamic = widgets.NewQCheckBox2("render message templates per send", nil)
+	p.tags = newKVTable("tag", "value")
+	p.payloadKind = widgets.NewQComboBox(nil)
+	p.payloadKind.AddItems([]string{
+		session.PayloadJSON.String() + " from message",
+		session.PayloadJSONLines.String(),
+		session.PayloadBinary.String(),
+	})
+	p.payloadFile = widgets.NewQLineEdit2("", nil)
+	p.payloadFile.SetDisabled(true)
+	p.payloadBrowse = widgets.NewQPushButton2("browse", nil)
+	p.payloadBrowse.SetDisabled(true)
+	p.previewButton = widgets.NewQPushButton2("preview", nil)
+	p.previewButton.SetToolTip("render the messages of the first calls, templates included")
+	payloadLayout := widgets.NewQHBoxLayout()
+	payloadLayout.AddWidget(p.payloadKind, 0, 0)
+	payloadLayout.AddWidget(p.payloadFile, 1, 0)
+	payloadLayout.AddWidget(p.payloadBrowse, 0, 0)
+	payloadLayout.AddWidget(p.previewButton, 0, 0)
+
+	p.profileKind = widgets.NewQComboBox(nil)
+	for k := session.ProfileFixed; k <= session.ProfileConstantRate; k++ {
+		p.profileKind.AddItem(k.String(), nil)
+	}
+	p.profileStart = widgets.NewQLineEdit2("", nil)
+	p.profileEnd = widgets.NewQLineEdit2("", nil)
+	p.profileStep = widgets.NewQLineEdit2("", nil)
+	p.profileStepDuration = widgets.NewQLineEdit2("", nil)
+	p.profileStepDuration.SetToolTip("results are reported per step, ramps included")
+	profileLayout := widgets.NewQHBoxLayout()
+	profileLayout.AddWidget(p.profileKind, 0, 0)
+	for _, f := range []struct {
+		label string
+		edit  *widgets.QLineEdit
+	}{
+		{"start", p.profileStart},
+		{"end", p.profileEnd},
+		{"step", p.profileStep},
+		{"every", p.profileStepDuration},
+	} {
+		profileLayout.AddWidget(widgets.NewQLabel2(f.label, nil, 0), 0, 0)
+		profileLayout.AddWidget(f.edit, 1, 0)
+	}
+
+	p.assertions = widgets.NewQTextEdit2("", nil)
+	p.assertions.SetPlaceholderText("one per line, e.g.\np99 < 200ms\nerror_rate < 0.1%\nrps >= 1000")
+	p.assertions.SetMaximumHeight(80)
+
+	layout := widgets.NewQGridLayout2()
+	row := func(r, c int, label string, w widgets.QWidget_ITF) {
+		layout.AddWidget(widgets.NewQLabel2(label, nil, 0), r, c, 0)
+		layout.AddWidget(w, r, c+1, 0)
+	}
+	row(0, 0, "run name", p.name)
+	row(1, 0, "total requests", p.totalRequests)
+	row(2, 0, "concurrency", p.concurrency)
+	row(3, 0, "connections", p.connections)
+	row(4, 0, "max duration", p.duration)
+	row(5, 0, "rate limit (rps)", p.rps)
+	row(6, 0, "request timeout", p.timeout)
+	row(0, 2, "keepalive", p.keepalive)
+	row(1, 2, "stream interval", p.streamInterval)
+	row(2, 2, "stream call duration", p.streamCallDuration)
+	row(3, 2, "stream call count", p.streamCallCount)
+	layout.AddWidget(p.streamDynamic, 4, 3, 0)
+	layout.AddWidget(widgets.NewQLabel2("tags", nil, 0), 5, 2, 0)
+	layout.AddWidget3(p.tags, 5, 3, 2, 1, 0)
+	layout.AddWidget(widgets.NewQLabel2("payload", nil, 0), 7, 0, 0)
+	layout.AddLayout(payloadLayout, 7, 1, 0)
+	layout.AddWidget(widgets.NewQLabel2("load profile", nil, 0), 8, 0, 0)
+	layout.AddLayout(profileLayout, 8, 1, 0)
+	layout.AddWidget(widgets.NewQLabel2("assertions", nil, 0), 7, 2, 0)
+	layout.AddWidget3(p.assertions, 7, 3, 2, 1, 0)
+	p.SetLayout(layout)
+
+	profileChanged := func(index int) {
+		kind := session.ProfileKind(index)
+		p.profileStart.SetDisabled(kind == session.ProfileFixed)
+		for _, w := range []*widgets.QLineEdit{p.profileEnd, p.profileStep, p.profileStepDuration} {
+			w.SetDisabled(kind == session.ProfileFixed || kind == session.ProfileConstantRate)
+		}
+	}
+	p.profileKind.ConnectCurrentIndexChanged(profileChanged)
+
+	p.payloadKind.ConnectCurrentIndexChanged(func(index int) {
+		fromFile := session.PayloadKind(index) != session.PayloadJSON
+		p.payloadFile.SetDisabled(!fromFile)
+		p.payloadBrowse.SetDisabled(!fromFile)
+	})
+
+	p.payloadBrowse.ConnectClicked(func(checked bool) {
+		filter := "JSON lines (*.jsonl *.ndjson *.json);;All files (*)"
+		if session.PayloadKind(p.payloadKind.CurrentIndex()) == session.PayloadBinary {
+			filter = "Protobuf (*.bin *.pb);;All files (*)"
+		}
+		if file := widgets.QFileDialog_GetOpenFileName(p, "payload file", "", filter, "", 0); file != "" {
+			p.payloadFile.SetText(file)
+		}
+	})
+
+	p.SetOptions(session.DefaultLoadTestOptions())
+	profileChanged(p.profileKind.CurrentIndex())
+	return p
+}
+
+// Options parses the fields, naming the first invalid one in the error.
+func (p *loadTestPanel) Options() (session.LoadTestOptions, error) {
+	var (
+		opts session.LoadTestOptions
+		err  error
+	)
+	opts.Name = p.name.Text()
+	uints := []struct {
+		name  string
+		edit  *widgets.QLineEdit
+		value *uint
+	}{
+		{"total requests", p.totalRequests, &opts.TotalRequests},
+		{"concurrency", p.concurrency, &opts.Concurrency},
+		{"connections", p.connections, &opts.Connections},
+		{"rate limit", p.rps, &opts.RPS},
+		{"stream call count", p.streamCallCount, &opts.StreamCallCount},
+		{"profile start", p.profileStart, &opts.Profile.Start},
+		{"profile end", p.profileEnd, &opts.Profile.End},
+	}
+	for _, u := range uints {
+		if *u.value, err = parseUint(u.edit.Text()); err != nil {
+			return opts, fmt.Errorf("Invalid %s: %s", u.name, err.Error())
+		}
+	}
+	durations := []struct {
+		name  string
+		edit  *widgets.QLineEdit
+		value *time.Duration
+	}{
+		{"max duration", p.duration, &opts.Duration},
+		{"request timeout", p.timeout, &opts.Timeout},
+		{"keepalive", p.keepalive, &opts.Keepalive},
+		{"stream interval", p.streamInterval, &opts.StreamInterval},
+		{"stream call duration", p.streamCallDuration, &opts.StreamCallDuration},
+		{"profile step duration", p.profileStepDuration, &opts.Profile.StepDuration},
+	}
+	for _, d := range durations {
+		if *d.value, err = parseDuration(d.edit.Text()); err != nil {
+			return opts, fmt.Errorf("Invalid %s: %s", d.name, err.Error())
+		}
+	}
+	if opts.Concurrency == 0 {
+		return opts, fmt.Errorf("Invalid concurrency: must be at least 1")
+	}
+	opts.StreamDynamicMessages = p.streamDynamic.IsChecked()
+	opts.Profile.Kind = session.ProfileKind(p.profileKind.CurrentIndex())
+	if step := p.profileStep.Text(); step != "" {
+		if opts.Profile.Step, err = strconv.Atoi(step); err != nil {
+			return opts, fmt.Errorf("Invalid profile step: %s", err.Error())
+		}
+	}
+	if err := opts.Profile.Validate(); err != nil {
+		return opts, err
+	}
+	if pairs := p.tags.Pairs(); len(pairs) != 0 {
+		opts.Tags = map[string]string{}
+		for _, t := range pairs {
+			opts.Tags[t[0]] = t[1]
+		}
+	}
+	return opts, nil
+}
+
+// Payload returns the request messages picked, message being the json of the
+// message editor.
+func (p *loadTestPanel) Payload(message string) session.Payload {
+	return session.Payload{
+		Kind: session.PayloadKind(p.payloadKind.CurrentIndex()),
+		Text: message,
+		File: p.payloadFile.Text(),
+	}
+}
+
+// Assertions returns the thresholds lines, and the parsed thresholds.
+func (p *loadTestPanel) Assertions() ([]string, []session.Assertion, error) {
+	lines := strings.Split(p.assertions.ToPlainText(), "\n")
+	assertions, err := session.ParseAssertions(lines)
+	return lines, assertions, err
+}
+
+func (p *loadTestPanel) SetAssertions(lines []string) {
+	p.assertions.SetPlainText(strings.Join(lines, "\n"))
+}
+
+func (p *loadTestPanel) SetOptions(opts session.LoadTestOptions) {
+	p.name.SetText(opts.Name)
+	p.totalRequests.SetText(formatUint(opts.TotalRequests))
+	p.concurrency.SetText(formatUint(opts.Concurrency))
+	p.connections.SetText(formatUint(opts.Connections))
+	p.rps.SetText(formatUint(opts.RPS))
+	p.streamCallCount.SetText(formatUint(opts.StreamCallCount))
+	p.duration.SetText(formatDuration(opts.Duration))
+	p.timeout.SetText(formatDuration(opts.Timeout))
+	p.keepalive.SetText(formatDuration(opts.Keepalive))
+	p.streamInterval.SetText(formatDuration(opts.StreamInterval))
+	p.streamCallDuration.SetText(formatDuration(opts.StreamCallDuration))
+	p.streamDynamic.SetChecked(opts.StreamDynamicMessages)
+	p.profileKind.SetCurrentIndex(int(opts.Profile.Kind))
+	p.profileStart.SetText(formatUint(opts.Profile.Start))
+	p.profileEnd.SetText(formatUint(opts.Profile.End))
+	p.profileStep.SetText("")
+	if opts.Profile.Step != 0 {
+		p.profileStep.SetText(strconv.Itoa(opts.Profile.Step))
+	}
+	p.profileStepDuration.SetText(formatDuration(opts.Profile.StepDuration))
+	var tags [][2]string
+	for k, v := range opts.Tags {
+		tags = append(tags, [2]string{k, v})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i][0] < tags[j][0] })
+	p.tags.SetPairs(tags)
+}
+
+// empty fields are zero, which means unset to ghz
+func parseUint(s string) (uint, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 32)
+	return uint(v), err
+}
+
+func formatUint(v uint) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -3,44 +3,59 @@ package main
 import (
 	"context"
 	"fmt"
-	"github.com/bojand/ghz/printer"
-	"github.com/bojand/ghz/runner"
-	"github.com/fullstorydev/grpcurl"
-	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/RoomOfRequirement/qt_grpc/cli"
+	"github.com/RoomOfRequirement/qt_grpc/session"
 	"github.com/jhump/protoreflect/desc"
-	"github.com/jhump/protoreflect/grpcreflect"
 	"github.com/therecipe/qt/core"
 	"github.com/therecipe/qt/widgets"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials"
-	"io"
-	"regexp"
-	"runtime"
-	"strconv"
 	"strings"
 	"time"
-	"unsafe"
 
-	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"log"
 	"os"
+	"path/filepath"
 )
 
 type MainWindow struct {
 	*widgets.QWidget
 
 	// groupBox
-	configGroup  *widgets.QGroupBox
-	addressGroup *widgets.QGroupBox
-	reqGroup     *widgets.QGroupBox
-	respGroup    *widgets.QGroupBox
+	configGroup   *widgets.QGroupBox
+	addressGroup  *widgets.QGroupBox
+	reqGroup      *widgets.QGroupBox
+	loadTestGroup *widgets.QGroupBox
+	respGroup     *widgets.QGroupBox
+
+	// connections reused across button clicks
+	conns *session.Manager
+	// schema of every server seen, shared by all connections
+	schemas *session.Cache
+	// network operations running off the GUI thread
+	tasks *worker
+	// persisted between sessions
+	settings settings
+	// past load tests, nil when it could not be opened
+	history *session.History
 }
 
 func NewMainWindow(app *widgets.QApplication) (mainWindow *MainWindow) {
 	// mainWindow
 	mainWindow = &MainWindow{}
 	mainWindow.QWidget = widgets.NewQWidget(nil, 0)
+	mainWindow.schemas = session.NewCache("")
+	mainWindow.conns = session.NewManager(mainWindow.schemas)
+	st, err := loadSettings()
+	if err != nil {
+		log.Println("Failed to load settings due to:", err)
+	}
+	mainWindow.settings = st
+	if path, err := settingsPath(); err == nil {
+		mainWindow.history, err = session.OpenHistory(filepath.Join(filepath.Dir(path), "history"))
+		if err != nil {
+			log.Println(err)
+		}
+	}
 	mainWindow.SetMinimumHeight(800)
 	mainWindow.SetMinimumWidth(600)
 	mainWindow.SetWindowTitle("GRPC Descriptor")
@@ -48,14 +63,25 @@ func NewMainWindow(app *widgets.QApplication) (mainWindow *MainWindow) {
 	mainWindow.addressGroup = widgets.NewQGroupBox2("address", nil)
 	mainWindow.configGroup = widgets.NewQGroupBox2("config", nil)
 	mainWindow.reqGroup = widgets.NewQGroupBox2("request", nil)
+	mainWindow.loadTestGroup = widgets.NewQGroupBox2("load test", nil)
 	mainWindow.respGroup = widgets.NewQGroupBox2("response", nil)
 
 	// addressGroup
 	addressLabel := widgets.NewQLabel2("server address:", nil, 0)
 	addressLineEdit := widgets.NewQLineEdit2("localhost:10000", nil)
+	stateLabel := widgets.NewQLabel2("not connected", nil, 0)
+	busyBar := widgets.NewQProgressBar(nil)
+	busyBar.SetRange(0, 0)
+	busyBar.SetMaximumWidth(120)
+	busyBar.Hide()
+	cancelButton := widgets.NewQPushButton2("cancel", nil)
+	cancelButton.SetDisabled(true)
 	addressLayout := widgets.NewQGridLayout2()
 	addressLayout.AddWidget(addressLabel, 0, 0, 0)
 	addressLayout.AddWidget(addressLineEdit, 0, 1, 0)
+	addressLayout.AddWidget(stateLabel, 0, 2, 0)
+	addressLayout.AddWidget(busyBar, 0, 3, 0)
+	addressLayout.AddWidget(cancelButton, 0, 4, 0)
 	mainWindow.addressGroup.SetLayout(addressLayout)
 
 	// configGroup
@@ -63,542 +89,823 @@ func NewMainWindow(app *widgets.QApplication) (mainWindow *MainWindow) {
 	plainTextButton.SetCheckedDefault(true)
 	tlsButton := widgets.NewQRadioButton2("TLS", nil)
 	serverLabel := widgets.NewQLabel2("server name", nil, 0)
-	publicKeyLabel := widgets.NewQLabel2("public crt file path", nil, 0)
-	privateKeyLabel := widgets.NewQLabel2("private key file path", nil, 0)
+	rootCALabel := widgets.NewQLabel2("root CA file", nil, 0)
+	publicKeyLabel := widgets.NewQLabel2("client crt file", nil, 0)
+	privateKeyLabel := widgets.NewQLabel2("client key file", nil, 0)
 	serverName := widgets.NewQLineEdit2("", nil)
-	serverName.SetDisabledDefault(true)
-	publicKey := widgets.NewQLineEdit2("*", nil)
-	publicKey.SetDisabledDefault(true)
-	privateKey := widgets.NewQLineEdit2("*", nil)
-	privateKey.SetDisabledDefault(true)
+	serverName.SetPlaceholderText("from address")
+	rootCA := widgets.NewQLineEdit2("", nil)
+	rootCA.SetPlaceholderText("system roots")
+	rootCABrowse := widgets.NewQPushButton2("browse", nil)
+	publicKey := widgets.NewQLineEdit2("", nil)
+	publicKey.SetPlaceholderText("none")
+	publicKeyBrowse := widgets.NewQPushButton2("browse", nil)
+	privateKey := widgets.NewQLineEdit2("", nil)
+	privateKey.SetPlaceholderText("none")
+	privateKeyBrowse := widgets.NewQPushButton2("browse", nil)
+	skipVerifyBox := widgets.NewQCheckBox2("skip server certificate verification", nil)
+	tlsWidgets := []*widgets.QWidget{
+		serverName.QWidget_PTR(), rootCA.QWidget_PTR(), rootCABrowse.QWidget_PTR(),
+		publicKey.QWidget_PTR(), publicKeyBrowse.QWidget_PTR(),
+		privateKey.QWidget_PTR(), privateKeyBrowse.QWidget_PTR(), skipVerifyBox.QWidget_PTR(),
+	}
+	for _, w := range tlsWidgets {
+		w.SetDisabled(true)
+	}
+	authorityLabel := widgets.NewQLabel2(":authority", nil, 0)
+	authority := widgets.NewQLineEdit2("", nil)
+	authority.SetPlaceholderText("from address")
 	configGroupLayout := widgets.NewQGridLayout2()
 	configGroupLayout.AddWidget(plainTextButton, 0, 0, 0)
 	configGroupLayout.AddWidget(tlsButton, 0, 1, 0)
-	configGroupLayout.AddWidget(serverLabel, 1, 1, 0)
-	configGroupLayout.AddWidget(serverName, 1, 2, 0)
-	configGroupLayout.AddWidget(publicKeyLabel, 2, 1, 0)
-	configGroupLayout.AddWidget(publicKey, 2, 2, 0)
-	configGroupLayout.AddWidget(privateKeyLabel, 3, 1, 0)
-	configGroupLayout.AddWidget(privateKey, 3, 2, 0)
+	configGroupLayout.AddWidget(serverLabel, 1, 0, 0)
+	configGroupLayout.AddWidget(serverName, 1, 1, 0)
+	configGroupLayout.AddWidget(rootCALabel, 2, 0, 0)
+	configGroupLayout.AddWidget(rootCA, 2, 1, 0)
+	configGroupLayout.AddWidget(rootCABrowse, 2, 2, 0)
+	configGroupLayout.AddWidget(publicKeyLabel, 3, 0, 0)
+	configGroupLayout.AddWidget(publicKey, 3, 1, 0)
+	configGroupLayout.AddWidget(publicKeyBrowse, 3, 2, 0)
+	configGroupLayout.AddWidget(privateKeyLabel, 4, 0, 0)
+	configGroupLayout.AddWidget(privateKey, 4, 1, 0)
+	configGroupLayout.AddWidget(privateKeyBrowse, 4, 2, 0)
+	configGroupLayout.AddWidget(skipVerifyBox, 5, 1, 0)
+	configGroupLayout.AddWidget(authorityLabel, 6, 0, 0)
+	configGroupLayout.AddWidget(authority, 6, 1, 0)
+	diskCacheBox := widgets.NewQCheckBox2("cache schema on disk", nil)
+	diskCacheBox.SetCheckedDefault(false)
+	configGroupLayout.AddWidget(diskCacheBox, 3, 3, 0)
+
+	// schema source, for servers without reflection
+	schemaLabel := widgets.NewQLabel2("schema from", nil, 0)
+	schemaKind := widgets.NewQComboBox(nil)
+	schemaKind.AddItems([]string{
+		session.SchemaReflection.String(),
+		session.SchemaProtoFiles.String(),
+		session.SchemaProtosets.String(),
+	})
+	schemaFilesLabel := widgets.NewQLabel2("files (comma separated)", nil, 0)
+	schemaFiles := widgets.NewQLineEdit2("", nil)
+	schemaFiles.SetDisabledDefault(true)
+	schemaBrowseButton := widgets.NewQPushButton2("browse", nil)
+	schemaBrowseButton.SetDisabled(true)
+	importPathsLabel := widgets.NewQLabel2("import paths (comma separated)", nil, 0)
+	importPaths := widgets.NewQLineEdit2("", nil)
+	importPaths.SetDisabledDefault(true)
+	configGroupLayout.AddWidget(schemaLabel, 0, 3, 0)
+	configGroupLayout.AddWidget(schemaKind, 0, 4, 0)
+	configGroupLayout.AddWidget(schemaFilesLabel, 1, 3, 0)
+	configGroupLayout.AddWidget(schemaFiles, 1, 4, 0)
+	configGroupLayout.AddWidget(schemaBrowseButton, 1, 5, 0)
+	configGroupLayout.AddWidget(importPathsLabel, 2, 3, 0)
+	configGroupLayout.AddWidget(importPaths, 2, 4, 0)
 	mainWindow.configGroup.SetLayout(configGroupLayout)
 
 	//respGroup
 	respText := widgets.NewQTextEdit2("respText", nil)
-	respListGroup := widgets.NewQGroupBox2("list", nil)
-	respList := widgets.NewQListWidget(nil)
-	respListOp := widgets.NewQListWidget(nil)
-	respListOpOp := widgets.NewQTextEdit2("", nil)
-	respListGroupLayout := widgets.NewQGridLayout2()
-	respListGroupLayout.AddWidget(respList, 0, 1, 0)
-	respListGroupLayout.AddWidget(respListOp, 1, 1, 0)
-	respListGroupLayout.AddWidget(respListOpOp, 2, 1, 0)
-	respListGroup.SetLayout(respListGroupLayout)
+	schemaGroup := widgets.NewQGroupBox2("schema", nil)
+	schemaBrowser := newSchemaBrowser()
+	schemaGroupLayout := widgets.NewQVBoxLayout()
+	schemaGroupLayout.AddWidget(schemaBrowser, 1, 0)
+	schemaGroup.SetLayout(schemaGroupLayout)
+
+	errLabel := widgets.NewQLabel2("", nil, 0)
+	errLabel.SetStyleSheet("color: red")
+	errLabel.SetWordWrap(true)
+	errLabel.Hide()
+
+	// what the server sent back on the last call besides responses
+	headersText := widgets.NewQTextEdit2("", nil)
+	headersText.SetReadOnly(true)
+	trailersText := widgets.NewQTextEdit2("", nil)
+	trailersText.SetReadOnly(true)
+	statusText := widgets.NewQTextEdit2("", nil)
+	statusText.SetReadOnly(true)
+	respTabs := widgets.NewQTabWidget(nil)
+	respTabs.AddTab(respText, "response")
+	respTabs.AddTab(headersText, "headers")
+	respTabs.AddTab(trailersText, "trailers")
+	respTabs.AddTab(statusText, "status")
+	results := newResultsView()
+	respTabs.AddTab(results, "load test")
+	history := newHistoryView()
+	if mainWindow.history != nil {
+		history.SetRuns(mainWindow.history.Runs())
+	}
+	respTabs.AddTab(history, "history")
 
 	respLayout := widgets.NewQGridLayout2()
-	respLayout.AddWidget(respText, 0, 0, 0)
-	respLayout.AddWidget(respListGroup, 0, 1, 0)
+	respLayout.AddWidget(respTabs, 0, 0, 0)
+	respLayout.AddWidget(schemaGroup, 0, 1, 0)
+	respLayout.AddWidget3(errLabel, 1, 0, 1, 2, 0)
 	mainWindow.respGroup.SetLayout(respLayout)
 
 	// reqGroup
 	describeButton := widgets.NewQPushButton2("describeServer", nil)
 	listServicesButton := widgets.NewQPushButton2("listServices", nil)
+	refreshSchemaButton := widgets.NewQPushButton2("refresh schema", nil)
 	loadTestBox := widgets.NewQCheckBox2("loadingTest", nil)
 	loadTestBox.SetCheckedDefault(false)
 	sendCheckBox := widgets.NewQCheckBox2("message", nil)
 	sendCheckBox.SetCheckedDefault(false)
 	testStartButton := widgets.NewQPushButton2("start", nil)
 	testStartButton.SetDisabled(true)
-	sendText := widgets.NewQTextEdit2("message in json", nil)
+	sendText := widgets.NewQTextEdit2("", nil)
+	sendText.SetPlaceholderText("message in json")
 	sendText.SetDisabledDefault(true)
-	totalTestRequestsLabel := widgets.NewQLabel2("total requests", nil, 0)
-	totalTestRequests := widgets.NewQLineEdit2("500", nil)
-	totalTestRequests.SetDisabledDefault(true)
-	concurrencyLabel := widgets.NewQLabel2("concurrency", nil, 0)
-	concurrency := widgets.NewQLineEdit2("20", nil)
-	concurrency.SetDisabledDefault(true)
-	maxDurationLabel := widgets.NewQLabel2("max duration", nil, 0)
-	maxDuration := widgets.NewQLineEdit2("5", nil)
-	maxDuration.SetDisabledDefault(true)
+	requestForm := newRequestForm()
+	requestForm.SetDisabled(true)
+	requestTabs := widgets.NewQTabWidget(nil)
+	requestTabs.AddTab(sendText, "json")
+	formTab := requestTabs.AddTab(requestForm, "form")
 	methodNameLabel := widgets.NewQLabel2("methodName", nil, 0)
 	methodName := widgets.NewQLineEdit2("service.method", nil)
 	methodName.SetDisabledDefault(true)
 	sendButton := widgets.NewQPushButton2("send", nil)
 	sendButton.SetDisabled(true)
+	templateButton := widgets.NewQPushButton2("fill template", nil)
+	templateButton.SetToolTip("fill the message with the defaults of every field of the request type")
+	templateButton.SetDisabled(true)
+	streamKindLabel := widgets.NewQLabel2("", nil, 0)
+	halfCloseButton := widgets.NewQPushButton2("half-close", nil)
+	halfCloseButton.SetDisabled(true)
+	cancelCallButton := widgets.NewQPushButton2("cancel", nil)
+	cancelCallButton.SetDisabled(true)
+	metadataLabel := widgets.NewQLabel2("metadata", nil, 0)
+	metadataTable := newKVTable("key", "value (base64 for -bin keys)")
+	streamLayout := widgets.NewQHBoxLayout()
+	streamLayout.AddWidget(streamKindLabel, 0, 0)
+	streamLayout.AddWidget(halfCloseButton, 0, 0)
+	streamLayout.AddWidget(cancelCallButton, 0, 0)
 	reqLayout := widgets.NewQGridLayout2()
 	reqLayout.AddWidget(describeButton, 0, 0, 0)
 	reqLayout.AddWidget(listServicesButton, 0, 1, 1)
 	reqLayout.AddWidget(loadTestBox, 0, 2, 2)
 	reqLayout.AddWidget(testStartButton, 0, 3, 0)
 	reqLayout.AddWidget(sendCheckBox, 1, 0, 0)
-	reqLayout.AddWidget(sendText, 1, 1, 0)
-	reqLayout.AddWidget(totalTestRequestsLabel, 1, 2, 0)
-	reqLayout.AddWidget(totalTestRequests, 1, 3, 0)
+	reqLayout.AddWidget(requestTabs, 1, 1, 0)
 	reqLayout.AddWidget(methodNameLabel, 2, 0, 0)
 	reqLayout.AddWidget(methodName, 2, 1, 0)
-	reqLayout.AddWidget(concurrencyLabel, 2, 2, 0)
-	reqLayout.AddWidget(concurrency, 2, 3, 0)
+	reqLayout.AddWidget(refreshSchemaButton, 3, 0, 0)
 	reqLayout.AddWidget(sendButton, 3, 1, 0)
-	reqLayout.AddWidget(maxDurationLabel, 3, 2, 0)
-	reqLayout.AddWidget(maxDuration, 3, 3, 0)
+	reqLayout.AddWidget(templateButton, 3, 2, 0)
+	reqLayout.AddLayout(streamLayout, 4, 1, 0)
+	reqLayout.AddWidget(metadataLabel, 5, 0, 0)
+	reqLayout.AddWidget3(metadataTable, 5, 1, 1, 3, 0)
 	mainWindow.reqGroup.SetLayout(reqLayout)
 
+	// loadTestGroup
+	loadTestPanel := newLoadTestPanel()
+	loadTestPanel.SetDisabled(true)
+	loadTestPanel.SetOptions(mainWindow.settings.LoadTest)
+	loadTestPanel.SetAssertions(mainWindow.settings.Assertions)
+	progressBar := widgets.NewQProgressBar(nil)
+	progressBar.SetRange(0, 1)
+	progressBar.SetValue(0)
+	progressLabel := widgets.NewQLabel2("", nil, 0)
+	stopTestButton := widgets.NewQPushButton2("stop", nil)
+	stopTestButton.SetDisabled(true)
+	progressLayout := widgets.NewQHBoxLayout()
+	progressLayout.AddWidget(progressBar, 1, 0)
+	progressLayout.AddWidget(progressLabel, 2, 0)
+	progressLayout.AddWidget(stopTestButton, 0, 0)
+	loadTestLayout := widgets.NewQVBoxLayout()
+	loadTestLayout.AddWidget(loadTestPanel, 0, 0)
+	loadTestLayout.AddLayout(progressLayout, 0)
+	mainWindow.loadTestGroup.SetLayout(loadTestLayout)
+
 	// mainWindow layout
 	grid := *widgets.NewQGridLayout2()
 	grid.AddWidget(mainWindow.addressGroup, 0, 0, 0)
 	grid.AddWidget(mainWindow.configGroup, 1, 0, 0)
 	grid.AddWidget(mainWindow.reqGroup, 2, 0, 0)
-	grid.AddWidget(mainWindow.respGroup, 3, 0, 0)
+	grid.AddWidget(mainWindow.loadTestGroup, 3, 0, 0)
+	grid.AddWidget(mainWindow.respGroup, 4, 0, 0)
 
 	mainWindow.SetLayout(&grid)
 
 	// button clicked function
 	tlsButton.ConnectClicked(func(checked bool) {
-		serverName.SetDisabled(false)
-		publicKey.SetDisabled(false)
-		privateKey.SetDisabled(false)
+		for _, w := range tlsWidgets {
+			w.SetDisabled(false)
+		}
 	})
 
 	plainTextButton.ConnectClicked(func(checked bool) {
-		serverName.SetDisabled(true)
-		publicKey.SetDisabled(true)
-		privateKey.SetDisabled(true)
+		for _, w := range tlsWidgets {
+			w.SetDisabled(true)
+		}
 	})
 
 	sendCheckBox.ConnectClicked(func(checked bool) {
 		sendText.SetDisabled(!sendCheckBox.IsChecked())
+		requestForm.SetDisabled(!sendCheckBox.IsChecked())
 		sendButton.SetDisabled(!sendCheckBox.IsChecked())
+		templateButton.SetDisabled(!sendCheckBox.IsChecked())
 		methodName.SetDisabled(!sendCheckBox.IsChecked())
 	})
 
-	describeButton.ConnectClicked(func(checked bool) {
-		resp := describe(addressLineEdit.Text(), plainTextButton.IsChecked(), serverName.Text(), &CA{false, "", publicKey.Text(), privateKey.Text()})
-		respText.SetText(resp)
+	newSession := func() *session.Session {
+		return mainWindow.conns.Get(session.Config{
+			Address:    addressLineEdit.Text(),
+			PlainText:  plainTextButton.IsChecked(),
+			ServerName: serverName.Text(),
+			CA: session.CA{
+				Insecure: skipVerifyBox.IsChecked(),
+				CACert:   rootCA.Text(),
+				Cert:     publicKey.Text(),
+				Key:      privateKey.Text(),
+			},
+			Authority: authority.Text(),
+			Schema: session.Schema{
+				Kind:        session.SchemaKind(schemaKind.CurrentIndex()),
+				Files:       splitList(schemaFiles.Text()),
+				ImportPaths: splitList(importPaths.Text()),
+			},
+			Metadata: requestMetadata(metadataTable),
+		})
+	}
+
+	schemaKind.ConnectCurrentIndexChanged(func(index int) {
+		kind := session.SchemaKind(index)
+		schemaFiles.SetDisabled(kind == session.SchemaReflection)
+		schemaBrowseButton.SetDisabled(kind == session.SchemaReflection)
+		importPaths.SetDisabled(kind != session.SchemaProtoFiles)
 	})
 
-	listServicesButton.ConnectClicked(func(checked bool) {
-		resp := listServices(addressLineEdit.Text(), plainTextButton.IsChecked(), serverName.Text(), &CA{false, "", publicKey.Text(), privateKey.Text()})
-		respText.SetText(resp)
-		s := strings.Split(resp, "\n")
-		respList.Clear()
-		for _, i := range s[:len(s)-1] {
-			newListItem := widgets.NewQListWidgetItem2(i, nil, 0)
-			respList.AddItem2(newListItem)
+	schemaBrowseButton.ConnectClicked(func(checked bool) {
+		filter := "Protobuf (*.proto)"
+		if session.SchemaKind(schemaKind.CurrentIndex()) == session.SchemaProtosets {
+			filter = "Protoset (*.protoset *.pb *.bin);;All files (*)"
+		}
+		files := widgets.QFileDialog_GetOpenFileNames(mainWindow, "schema files", "", filter, "", 0)
+		if len(files) != 0 {
+			schemaFiles.SetText(strings.Join(files, ","))
 		}
 	})
 
-	loadTestBox.ConnectClicked(func(checked bool) {
-		totalTestRequests.SetDisabled(!loadTestBox.IsChecked())
-		concurrency.SetDisabled(!loadTestBox.IsChecked())
-		maxDuration.SetDisabled(!loadTestBox.IsChecked())
-		testStartButton.SetDisabled(!loadTestBox.IsChecked())
+	// connectivity state of the current connection
+	stateTimer := core.NewQTimer(mainWindow)
+	stateTimer.ConnectTimeout(func() {
+		state := "not connected"
+		if s := mainWindow.conns.Current(); s != nil {
+			if st, ok := s.State(); ok {
+				state = strings.ToLower(st.String())
+			}
+		}
+		stateLabel.SetText(state)
 	})
+	stateTimer.Start(500)
 
-	respList.ConnectClicked(func(index *core.QModelIndex) {
-		svc := respList.SelectedItems()[0].Text()
-		resp := listMethods(addressLineEdit.Text(), svc, plainTextButton.IsChecked(), serverName.Text(), &CA{false, "", publicKey.Text(), privateKey.Text()})
-		s := strings.Split(resp, "\n")
-		respListOp.Clear()
-		for _, i := range s[:len(s)-1] {
-			newListItem := widgets.NewQListWidgetItem2(i, nil, 0)
-			respListOp.AddItem2(newListItem)
+	addressLineEdit.ConnectEditingFinished(func() {
+		if s := mainWindow.conns.Current(); s != nil && s.Config().Address != addressLineEdit.Text() {
+			_ = mainWindow.conns.Close()
 		}
 	})
 
-	respListOp.ConnectClicked(func(index *core.QModelIndex) {
-		method := respListOp.SelectedItems()[0].Text()
-		resp := methodDetails(addressLineEdit.Text(), method, plainTextButton.IsChecked(), serverName.Text(), &CA{false, "", publicKey.Text(), privateKey.Text()})
-		respListOpOp.SetText(resp)
+	app.ConnectAboutToQuit(func() {
+		_ = mainWindow.conns.Close()
+		if opts, err := loadTestPanel.Options(); err == nil {
+			mainWindow.settings.LoadTest = opts
+			mainWindow.settings.Assertions, _, _ = loadTestPanel.Assertions()
+			if err := mainWindow.settings.save(); err != nil {
+				log.Println("Failed to save settings due to:", err)
+			}
+		}
 	})
 
-	sendButton.ConnectClicked(func(checked bool) {
-		methodName := methodName.Text()
-		if methodName != "" {
-			res := invoke(addressLineEdit.Text(), plainTextButton.IsChecked(), serverName.Text(), &CA{false, publicKey.Text(), "", privateKey.Text()}, methodName, sendText.ToPlainText())
-			respText.SetText(res)
-		} else {
+	// errors are shown below the results instead of replacing them
+	showError := func(err error) {
+		errLabel.SetText(err.Error())
+		errLabel.Show()
+	}
+
+	clearError := func() {
+		errLabel.Clear()
+		errLabel.Hide()
+	}
+
+	// the json and the form tab hold the same message, synced on switching
+	requestTabs.ConnectCurrentChanged(func(index int) {
+		if requestForm.Message() == nil {
+			return
+		}
+		clearError()
+		if index == formTab {
+			if err := requestForm.SetJSON(sendText.ToPlainText()); err != nil {
+				showError(err)
+			}
 			return
 		}
+		text, err := requestForm.JSON()
+		if err != nil {
+			showError(err)
+			return
+		}
+		sendText.SetText(text)
 	})
 
-	testStartButton.ConnectClicked(func(checked bool) {
-		methodName := methodName.Text()
-		if methodName != "" {
-			// concurrency
-			cc, err := strconv.ParseUint(concurrency.Text(), 10, 32)
+	// requestText is the message to send, taken from the form when it is shown
+	requestText := func() (string, error) {
+		if requestTabs.CurrentIndex() == formTab && requestForm.Message() != nil {
+			text, err := requestForm.JSON()
 			if err != nil {
-				respText.SetText(err.Error())
-				return
-			}
-			// total requests
-			ttr, err := strconv.ParseUint(totalTestRequests.Text(), 10, 32)
-			if err != nil {
-				respText.SetText(err.Error())
-				return
+				return "", err
 			}
+			sendText.SetText(text)
+		}
+		return sendText.ToPlainText(), nil
+	}
 
-			// max duration
-			md, err := strconv.ParseUint(maxDuration.Text(), 10, 32)
-			if err != nil {
-				respText.SetText(err.Error())
-				return
-			}
+	mainWindow.tasks = newWorker(mainWindow, func(busy bool) {
+		busyBar.SetVisible(busy)
+		cancelButton.SetDisabled(!busy)
+	})
+
+	cancelButton.ConnectClicked(func(checked bool) {
+		mainWindow.tasks.Cancel()
+	})
+
+	// PEM files are checked as soon as they are picked, not on the next call
+	checkCA := func() {
+		ca := session.CA{CACert: rootCA.Text(), Cert: publicKey.Text(), Key: privateKey.Text()}
+		if ca.Cert == "" || ca.Key == "" {
+			ca.Cert, ca.Key = "", ""
+		}
+		if err := ca.Validate(); err != nil {
+			showError(err)
+			return
+		}
+		clearError()
+	}
+	browsePEM := func(edit *widgets.QLineEdit, caption string) {
+		file := widgets.QFileDialog_GetOpenFileName(mainWindow, caption, "", "PEM (*.pem *.crt *.cer *.key);;All files (*)", "", 0)
+		if file != "" {
+			edit.SetText(file)
+			checkCA()
+		}
+	}
+	rootCABrowse.ConnectClicked(func(checked bool) {
+		browsePEM(rootCA, "root CA file")
+	})
+	publicKeyBrowse.ConnectClicked(func(checked bool) {
+		browsePEM(publicKey, "client certificate file")
+	})
+	privateKeyBrowse.ConnectClicked(func(checked bool) {
+		browsePEM(privateKey, "client key file")
+	})
+	for _, edit := range []*widgets.QLineEdit{rootCA, publicKey, privateKey} {
+		edit.ConnectEditingFinished(checkCA)
+	}
 
-			// cpu
-			nCPU := runtime.GOMAXPROCS(-1)
-
-			/*
-				// set up all the options
-				// https://github.com/bojand/ghz/blob/master/cmd/ghz/main.go
-				// https://github.com/bojand/ghz/blob/master/runner/options.go
-				options := make([]runner.Option, 0, 15)
-
-				options = append(options,
-
-					// runner.WithProtoFile(cfg.Proto, cfg.ImportPaths),
-					// runner.WithProtoset(cfg.Protoset),
-					// runner.WithRootCertificate(cfg.RootCert),
-					// runner.WithCertificate(cfg.Cert, cfg.Key),
-					// runner.WithServerNameOverride(cfg.CName),
-					// runner.WithSkipTLSVerify(cfg.SkipTLSVerify),
-					runner.WithInsecure(true),
-					// runner.WithAuthority(cfg.Authority),
-					runner.WithConcurrency(uint(cc)),
-					runner.WithTotalRequests(uint(ttr)),
-					// runner.WithQPS(cfg.QPS),
-					runner.WithTimeout(20*time.Second),
-					runner.WithRunDuration(time.Duration(uint(md))*time.Second),
-					runner.WithDialTimeout(10*time.Second),
-					// runner.WithKeepalive(time.Duration(cfg.KeepaliveTime)),
-					// runner.WithName(cfg.Name),
-					runner.WithCPUs(uint(nCPU)),
-					// runner.WithMetadata(cfg.Metadata),
-					// runner.WithTags(cfg.Tags),
-					// runner.WithStreamInterval(time.Duration(cfg.SI)),
-					// runner.WithReflectionMetadata(cfg.ReflectMetadata),
-					runner.WithConnections(1),
-				)
-			*/
-
-			report, err := runner.Run(
-				methodName,
-				addressLineEdit.Text(),
-				runner.WithInsecure(true),
-				runner.WithConcurrency(uint(cc)),
-				runner.WithTotalRequests(uint(ttr)),
-				runner.WithTimeout(20*time.Second),
-				runner.WithRunDuration(time.Duration(uint(md))*time.Second),
-				runner.WithDialTimeout(10*time.Second),
-				runner.WithCPUs(uint(nCPU)),
-				runner.WithConnections(1),
-				runner.WithDataFromJSON(sendText.ToPlainText()))
+	results.saveButton.ConnectClicked(func(checked bool) {
+		format := results.Format()
+		file := widgets.QFileDialog_GetSaveFileName(mainWindow, "save report", "", reportFilter(format), "", 0)
+		if file == "" {
+			return
+		}
+		clearError()
+		if err := session.SaveReport(file, results.report, format); err != nil {
+			showError(err)
+		}
+	})
 
+	history.showButton.ConnectClicked(func(checked bool) {
+		if runs := history.Selected(); len(runs) != 0 {
+			run := runs[len(runs)-1]
+			results.SetReport(run.Report, run.Options.Profile)
+			// checked against the thresholds of today, not those of the run
+			_, assertions, err := loadTestPanel.Assertions()
 			if err != nil {
-				//panic(err)
-				respText.SetText(err.Error())
-				return
+				showError(err)
 			}
+			results.SetAssertions(session.Check(run.Report, assertions))
+			respTabs.SetCurrentWidget(results)
+		}
+	})
 
-			done := capture()
+	history.compareButton.ConnectClicked(func(checked bool) {
+		clearError()
+		runs := history.Selected()
+		if len(runs) != 2 {
+			showError(fmt.Errorf("Select two runs to compare, got %d", len(runs)))
+			return
+		}
+		history.Compare(runs[0], runs[1])
+	})
 
-			p := printer.ReportPrinter{
-				Report: report,
-				Out:    os.Stdout,
+	history.removeButton.ConnectClicked(func(checked bool) {
+		if mainWindow.history == nil {
+			return
+		}
+		clearError()
+		for _, run := range history.Selected() {
+			if err := mainWindow.history.Remove(run.ID); err != nil {
+				showError(err)
 			}
+		}
+		history.SetRuns(mainWindow.history.Runs())
+	})
 
-			err = p.Print("summary")
+	diskCacheBox.ConnectClicked(func(checked bool) {
+		if !diskCacheBox.IsChecked() {
+			mainWindow.schemas.SetDir("")
+			return
+		}
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			showError(err)
+			diskCacheBox.SetChecked(false)
+			return
+		}
+		mainWindow.schemas.SetDir(filepath.Join(dir, "qt_grpc"))
+	})
 
-			if err != nil {
-				if errString := err.Error(); errString != "" {
-					respText.SetText(errString)
+	refreshSchemaButton.ConnectClicked(func(checked bool) {
+		clearError()
+		s := newSession()
+		mainWindow.tasks.Go(func(ctx context.Context) func() {
+			err := s.RefreshSchema(ctx)
+			var files []*desc.FileDescriptor
+			if err == nil {
+				files, err = s.SchemaFiles(ctx)
+			}
+			return func() {
+				if err != nil {
+					schemaBrowser.Clear()
+					showError(err)
+					return
 				}
+				schemaBrowser.SetFiles(files)
 			}
+		})
+	})
 
-			str, err := done()
-			if err != nil {
-				respText.SetText(err.Error())
-				return
+	describeButton.ConnectClicked(func(checked bool) {
+		clearError()
+		s := newSession()
+		mainWindow.tasks.Go(func(ctx context.Context) func() {
+			symbols, err := s.Describe(ctx)
+			return func() {
+				if err != nil {
+					showError(err)
+					return
+				}
+				respText.SetText(symbolsText(symbols))
 			}
-			// this is a simple solution but not fit for large amount of requests (too many blank spaces...)
-			width := 4 * (2 << uint(len(totalTestRequests.Text())))
-			respText.SetTabStopWidth(width)
-			respText.SetText(str)
-		} else {
-			return
-		}
+		})
 	})
 
-	return
-}
-
-func String(b []byte) string {
-	return *(*string)(unsafe.Pointer(&b))
-}
+	listServicesButton.ConnectClicked(func(checked bool) {
+		clearError()
+		s := newSession()
+		mainWindow.tasks.Go(func(ctx context.Context) func() {
+			svcs, err := s.ListServices(ctx)
+			var files []*desc.FileDescriptor
+			if err == nil {
+				files, err = s.SchemaFiles(ctx)
+			}
+			return func() {
+				if err != nil {
+					showError(err)
+					return
+				}
+				schemaBrowser.SetFiles(files)
+				if len(svcs) == 0 {
+					respText.SetText("No services\n")
+					return
+				}
+				res := ""
+				for _, svc := range svcs {
+					res += svc.Name + "\n"
+				}
+				respText.SetText(res)
+			}
+		})
+	})
 
-type CA struct {
-	insecure        bool
-	cacert          string // file name string
-	pubKey, privKey string
-}
+	loadTestBox.ConnectClicked(func(checked bool) {
+		loadTestPanel.SetDisabled(!loadTestBox.IsChecked())
+		testStartButton.SetDisabled(!loadTestBox.IsChecked())
+	})
 
-func generateCreds(plainText bool, serverName string, ca *CA) (creds credentials.TransportCredentials, err error) {
-	if !plainText {
-		creds, err = grpcurl.ClientTransportCredentials(ca.insecure, ca.cacert, ca.pubKey, ca.privKey)
-		if err != nil {
-			return creds, fmt.Errorf("Failed to configure transport credentials due to: %s\n", err.Error())
-		}
-		if serverName != "" {
-			if err := creds.OverrideServerName(serverName); err != nil {
-				return creds, fmt.Errorf("Failed to override server name as %q due to: %s\n", serverName, err.Error())
+	// resolveMethod shows the streaming type of the method and sets up the form
+	resolveMethod := func(name string) {
+		s := newSession()
+		mainWindow.tasks.Go(func(ctx context.Context) func() {
+			m, err := s.ResolveMethod(ctx, name)
+			return func() {
+				if err != nil {
+					streamKindLabel.Clear()
+					return
+				}
+				streamKindLabel.SetText(m.Kind().String())
+				requestForm.SetMessage(m.Descriptor.GetInputType())
 			}
-		}
+		})
 	}
-	return
-}
 
-func dial(ctx context.Context, address string, creds credentials.TransportCredentials) (*grpc.ClientConn, context.Context, error) {
-	cc, err := grpcurl.BlockingDial(ctx, "tcp", address, creds)
-	if err != nil {
-		err = fmt.Errorf("Failed to dial target.host %q\n%s\n", address, err.Error())
+	// selecting a method in the schema makes it the one to call
+	schemaBrowser.onMethod = func(name string) {
+		clearError()
+		methodName.SetText(name)
+		resolveMethod(name)
 	}
-	return cc, ctx, err
-}
 
-func client(ctx context.Context, address string, plainText bool, serverName string, ca *CA) (*grpcreflect.Client, context.Context, error) {
-	creds, err := generateCreds(plainText, serverName, ca)
-	cc, ctx, err := dial(ctx, address, creds)
-	refClient := grpcreflect.NewClient(ctx, rpb.NewServerReflectionClient(cc))
-	return refClient, ctx, err
-}
+	methodName.ConnectEditingFinished(func() {
+		resolveMethod(methodName.Text())
+	})
 
-func descSource(address string, plainText bool, serverName string, ca *CA) (grpcurl.DescriptorSource, context.CancelFunc, error) {
-	dialTime := 10 * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), dialTime)
-	// defer cancel()
-	refClient, ctx, err := client(ctx, address, plainText, serverName, ca)
-	descSource := grpcurl.DescriptorSourceFromServer(ctx, refClient)
-	return descSource, cancel, err
-}
+	templateButton.ConnectClicked(func(checked bool) {
+		clearError()
+		name := methodName.Text()
+		s := newSession()
+		mainWindow.tasks.Go(func(ctx context.Context) func() {
+			m, err := s.ResolveMethod(ctx, name)
+			return func() {
+				if err != nil {
+					showError(err)
+					return
+				}
+				text := session.MessageTemplate(m.Descriptor.GetInputType())
+				sendText.SetText(text)
+				requestForm.SetMessage(m.Descriptor.GetInputType())
+				if requestTabs.CurrentIndex() == formTab {
+					if err := requestForm.SetJSON(text); err != nil {
+						showError(err)
+					}
+				}
+			}
+		})
+	})
 
-func parseReq(symbols []string, ds grpcurl.DescriptorSource) string {
-	res := ""
-	for _, s := range symbols {
-		if s[0] == '.' {
-			s = s[1:]
+	// active call of the send panel, its events are polled on the GUI thread
+	var (
+		stream       *session.Stream
+		streamMethod string // the methodName the stream was opened with
+		opening      bool
+	)
+	streamTimer := core.NewQTimer(mainWindow)
+	streamTimer.ConnectTimeout(func() {
+		for {
+			select {
+			case ev, ok := <-stream.Events():
+				if !ok {
+					streamTimer.Stop()
+					stream = nil
+					halfCloseButton.SetDisabled(true)
+					cancelCallButton.SetDisabled(true)
+					return
+				}
+				switch {
+				case ev.Err != nil:
+					showError(ev.Err)
+				case ev.Done:
+					trailersText.SetText(session.FormatMetadata(ev.Info.Trailers))
+					statusText.SetText(ev.Info.StatusText())
+					if ev.Info.Status.Code() != codes.OK {
+						showError(ev.Info.Status.Err())
+						respTabs.SetCurrentWidget(statusText)
+					}
+				case ev.Headers != nil:
+					headersText.SetText(session.FormatMetadata(ev.Headers))
+				case ev.Response != "":
+					respText.Append(ev.Response)
+				}
+			default:
+				return
+			}
 		}
+	})
 
-		dsc, err := ds.FindSymbol(s)
-		if err != nil {
-			return fmt.Sprintf("Failed to resolve symbol %q due to %s\n", s, err.Error())
-		}
-
-		fqn := dsc.GetFullyQualifiedName()
-		var elementType string
-		switch d := dsc.(type) {
-		case *desc.MessageDescriptor:
-			elementType = "a message"
-			parent, ok := d.GetParent().(*desc.MessageDescriptor)
-			if ok {
-				if d.IsMapEntry() {
-					for _, f := range parent.GetFields() {
-						if f.IsMap() && f.GetMessageType() == d {
-							// found it: describe the map field instead
-							elementType = "the entry type for a map field"
-							dsc = f
-							break
-						}
+	sendButton.ConnectClicked(func(checked bool) {
+		methodName := methodName.Text()
+		if methodName != "" && !opening {
+			clearError()
+			msg, err := requestText()
+			if err != nil {
+				showError(err)
+				return
+			}
+			if stream != nil {
+				if methodName != streamMethod {
+					showError(fmt.Errorf("Call to %s is still open, cancel it before calling %s", streamMethod, methodName))
+					return
+				}
+				if _, err := stream.Send(msg); err != nil {
+					showError(err)
+				}
+				return
+			}
+			opening = true
+			s := newSession()
+			mainWindow.tasks.Go(func(ctx context.Context) func() {
+				st, err := s.OpenStream(ctx, methodName)
+				return func() {
+					opening = false
+					if err != nil {
+						showError(err)
+						return
 					}
-				} else {
-					// see if it's a group
-					for _, f := range parent.GetFields() {
-						if f.GetType() == descpb.FieldDescriptorProto_TYPE_GROUP && f.GetMessageType() == d {
-							// found it: describe the map field instead
-							elementType = "the type of a group field"
-							dsc = f
-							break
-						}
+					stream, streamMethod = st, methodName
+					respText.Clear()
+					headersText.Clear()
+					trailersText.Clear()
+					statusText.Clear()
+					respTabs.SetCurrentWidget(respText)
+					streamKindLabel.SetText(st.Method.Kind().String())
+					halfCloseButton.SetDisabled(!st.Method.ClientStreaming)
+					cancelCallButton.SetDisabled(false)
+					streamTimer.Start(50)
+					if _, err := stream.Send(msg); err != nil {
+						showError(err)
 					}
 				}
-			}
-		case *desc.FieldDescriptor:
-			elementType = "a field"
-			if d.GetType() == descpb.FieldDescriptorProto_TYPE_GROUP {
-				elementType = "a group field"
-			} else if d.IsExtension() {
-				elementType = "an extension"
-			}
-		case *desc.OneOfDescriptor:
-			elementType = "a one-of"
-		case *desc.EnumDescriptor:
-			elementType = "an enum"
-		case *desc.EnumValueDescriptor:
-			elementType = "an enum value"
-		case *desc.ServiceDescriptor:
-			elementType = "a service"
-		case *desc.MethodDescriptor:
-			elementType = "a method"
-		default:
-			err = fmt.Errorf("descriptor has unrecognized type %T", dsc)
-			return fmt.Sprintf("Failed to describe symbol %q due to %s\n", s, err.Error())
-		}
-
-		txt, err := grpcurl.GetDescriptorText(dsc, ds)
-		if err != nil {
-			return fmt.Sprintf("Failed to describe symbol %q due to %s\n", s, err.Error())
+			})
+		} else {
+			return
 		}
+	})
 
-		res += fmt.Sprintf("%s is %s:\n", fqn, elementType) + fmt.Sprintln(txt) + "\n"
-	}
-	return res
-}
-
-func capture() func() (string, error) {
-	r, w, err := os.Pipe()
-	if err != nil {
-		panic(err)
-	}
-
-	done := make(chan error, 1)
-
-	save := os.Stdout
-	os.Stdout = w
-
-	var buf strings.Builder
-
-	go func() {
-		_, err := io.Copy(&buf, r)
-		_ = r.Close()
-		done <- err
-	}()
+	halfCloseButton.ConnectClicked(func(checked bool) {
+		if stream != nil {
+			stream.CloseSend()
+			halfCloseButton.SetDisabled(true)
+		}
+	})
 
-	return func() (string, error) {
-		os.Stdout = save
-		_ = w.Close()
-		err := <-done
-		return buf.String(), err
-	}
-}
+	cancelCallButton.ConnectClicked(func(checked bool) {
+		if stream != nil {
+			stream.Cancel()
+		}
+	})
 
-func invoke(address string, plainText bool, serverName string, ca *CA, methodName, msg string) string {
-	dialTime := 10 * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), dialTime)
-	defer cancel()
-	creds, err := generateCreds(plainText, serverName, ca)
-	if err != nil {
-		return err.Error()
-	}
-	cc, ctx, err := dial(ctx, address, creds)
-	if err != nil {
-		return err.Error()
-	}
-	refClient := grpcreflect.NewClient(ctx, rpb.NewServerReflectionClient(cc))
-	descSource := grpcurl.DescriptorSourceFromServer(ctx, refClient)
+	loadTestPanel.previewButton.ConnectClicked(func(checked bool) {
+		clearError()
+		method := methodName.Text()
+		msg, err := requestText()
+		if err != nil {
+			showError(err)
+			return
+		}
+		payload := loadTestPanel.Payload(msg)
+		s := newSession()
+		mainWindow.tasks.Go(func(ctx context.Context) func() {
+			msgs, err := s.PreviewPayloads(ctx, method, payload, 5)
+			return func() {
+				if err != nil {
+					showError(err)
+					return
+				}
+				respText.Clear()
+				respText.Append("// a sample, random values differ during the run\n")
+				for i, msg := range msgs {
+					respText.Append(fmt.Sprintf("// call %d\n%s\n", i+1, msg))
+				}
+				respTabs.SetCurrentWidget(respText)
+			}
+		})
+	})
 
-	rf, formatter, err := grpcurl.RequestParserAndFormatterFor(grpcurl.Format("json"), descSource, true, true, strings.NewReader(msg))
-	if err != nil {
-		return fmt.Sprintf("Failed to construct request parser and formatter for json due to: %s\n", err.Error())
-	}
-	done := capture()
-	h := grpcurl.NewDefaultEventHandler(os.Stdout, descSource, formatter, false)
-	err = grpcurl.InvokeRPC(ctx, descSource, cc, methodName, []string{}, h, rf.Next)
+	// the running load test, stopping it still shows the report so far
+	var (
+		monitor  *session.Monitor
+		stopTest context.CancelFunc = func() {}
+	)
+	stopTestButton.ConnectClicked(func(checked bool) {
+		stopTest()
+	})
 
-	if err != nil {
-		return fmt.Sprintf("Error invoking method %s due to: %s\n", methodName, err.Error())
+	progressTimer := core.NewQTimer(mainWindow)
+	showProgress := func(p session.Progress) {
+		if p.Total != 0 {
+			progressBar.SetRange(0, int(p.Total))
+			progressBar.SetValue(int(p.Completed))
+		} else {
+			progressBar.SetRange(0, 0)
+		}
+		text := fmt.Sprintf("%d done, %d errors, %.1f rps", p.Completed, p.Errors, p.RPS)
+		for _, l := range p.Latency {
+			text += fmt.Sprintf(", p%d %s", l.Percentage, l.Latency.Round(10*time.Microsecond))
+		}
+		progressLabel.SetText(text)
+		progressLabel.SetToolTip(fmt.Sprintf("elapsed %s\n%v", p.Elapsed.Round(time.Second), p.Statuses))
 	}
+	progressTimer.ConnectTimeout(func() {
+		if monitor != nil {
+			showProgress(monitor.Snapshot())
+		}
+	})
 
-	if h.Status.Code() != codes.OK {
-		return fmt.Sprint(h.Status)
-	}
+	testStartButton.ConnectClicked(func(checked bool) {
+		methodName := methodName.Text()
+		if methodName != "" {
+			clearError()
+			opts, err := loadTestPanel.Options()
+			if err != nil {
+				showError(err)
+				return
+			}
+			lines, assertions, err := loadTestPanel.Assertions()
+			if err != nil {
+				showError(err)
+				return
+			}
+			mainWindow.settings.LoadTest = opts
+			mainWindow.settings.Assertions = lines
+			if err := mainWindow.settings.save(); err != nil {
+				log.Println("Failed to save settings due to:", err)
+			}
 
-	str, err := done()
-	if err != nil {
-		return fmt.Sprintf("Error invoking method %s due to: %s\n", methodName, err.Error())
-	}
-	return str
-}
+			msg, err := requestText()
+			if err != nil {
+				showError(err)
+				return
+			}
+			payload := loadTestPanel.Payload(msg)
+			s := newSession()
+			mon := session.NewMonitor()
+			stopCtx, stop := context.WithCancel(context.Background())
+			monitor, stopTest = mon, stop
+			progressTimer.Start(250)
+			testStartButton.SetDisabled(true)
+			stopTestButton.SetDisabled(false)
+			mainWindow.tasks.Go(func(ctx context.Context) func() {
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				go func() {
+					select {
+					case <-stopCtx.Done():
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+				report, err := s.LoadTest(ctx, methodName, payload, opts, mon)
+				return func() {
+					stop()
+					progressTimer.Stop()
+					showProgress(mon.Snapshot())
+					progressBar.SetRange(0, 1)
+					progressBar.SetValue(1)
+					testStartButton.SetDisabled(!loadTestBox.IsChecked())
+					stopTestButton.SetDisabled(true)
+					if err != nil {
+						showError(err)
+						return
+					}
+					results.SetReport(report, opts.Profile)
+					results.SetAssertions(session.Check(report, assertions))
+					respTabs.SetCurrentWidget(results)
+					if mainWindow.history != nil {
+						run := session.Run{Method: methodName, Target: s.Config().Address, Options: opts, Report: report}
+						if _, err := mainWindow.history.Add(run); err != nil {
+							showError(err)
+						}
+						history.SetRuns(mainWindow.history.Runs())
+					}
+				}
+			})
+		} else {
+			return
+		}
+	})
 
-func describe(address string, plainText bool, serverName string, ca *CA) string {
-	ds, cancel, err := descSource(address, plainText, serverName, ca)
-	if err != nil {
-		return err.Error()
-	}
-	defer cancel()
-	svcs, err := grpcurl.ListServices(ds)
-	if err != nil {
-		return fmt.Sprintf("Failed to list services due to:\n %s\n", err.Error())
-	}
-	if len(svcs) == 0 {
-		return fmt.Sprint("Server returned an empty list of exposed services\n")
-	}
-	symbols := svcs
-	res := parseReq(symbols, ds)
-	return res
+	return
 }
 
-func listServices(address string, plainText bool, serverName string, ca *CA) string {
-	ds, cancel, err := descSource(address, plainText, serverName, ca)
-	if err != nil {
-		return err.Error()
-	}
-	defer cancel()
-	svcs, err := grpcurl.ListServices(ds)
-	if err != nil {
-		return fmt.Sprintf("Failed to list services due to:\n %s\n", err.Error())
-	}
-	if len(svcs) == 0 {
-		return fmt.Sprint("No services\n")
-	} else {
-		res := ""
-		for _, svc := range svcs {
-			res += svc + "\n"
-		}
-		return res
+func requestMetadata(t *kvTable) session.Metadata {
+	var md session.Metadata
+	for _, p := range t.Pairs() {
+		md = append(md, session.Header{Key: p[0], Value: p[1]})
 	}
+	return md
 }
 
-func listMethods(address, serviceName string, plainText bool, serverName string, ca *CA) string {
-	ds, cancel, err := descSource(address, plainText, serverName, ca)
-	if err != nil {
-		return err.Error()
-	}
-	defer cancel()
-	methods, err := grpcurl.ListMethods(ds, serviceName)
-	if err != nil {
-		return fmt.Sprintf("Failed to list methods due to:\n %s\n", err.Error())
-	}
-	if len(methods) == 0 {
-		return fmt.Sprint("No methods\n") // probably unlikely
-	} else {
-		res := ""
-		for _, method := range methods {
-			res += method + "\n"
-		}
-		return res
+// splitList splits a comma separated line edit value, ignoring blanks.
+func splitList(s string) []string {
+	var res []string
+	for _, i := range strings.Split(s, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			res = append(res, i)
+		}
 	}
+	return res
 }
 
-func methodDetails(address, methodName string, plainText bool, serverName string, ca *CA) string {
-	ds, cancel, err := descSource(address, plainText, serverName, ca)
-	if err != nil {
-		return err.Error()
-	}
-	defer cancel()
-	symbols := []string{methodName}
-	res := parseReq(symbols, ds) + "\n"
-	reg := regexp.MustCompile(`\( \.(.*?) \)`)
-	msgs := reg.FindAllStringSubmatch(res, -1)
-	// log.Println(msgs)
-	if len(msgs) != 0 {
-		var tmp []string
-		for _, msg := range msgs {
-			// log.Println(msg[1])
-			tmp = append(tmp, msg[1])
-		}
-		res += parseReq(tmp, ds)
+func symbolsText(symbols []session.Symbol) string {
+	res := ""
+	for _, s := range symbols {
+		res += s.String() + "\n"
 	}
 	return res
 }
 
 func main() {
+	// subcommands run headless, without a display
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	app := widgets.NewQApplication(len(os.Args), os.Args)
 	mainWindow := NewMainWindow(app)
 	mainWindow.Show()
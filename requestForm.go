@@ -0,0 +1,927 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RoomOfRequirement/qt_grpc/session"
+	"github.com/golang/protobuf/jsonpb"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/ptypes"
+	durpb "github.com/golang/protobuf/ptypes/duration"
+	tspb "github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/widgets"
+)
+
+// requestForm edits a request message field by field, as an alternative to
+// writing its json by hand.
+type requestForm struct {
+	*widgets.QScrollArea
+
+	md   *desc.MessageDescriptor
+	root *messageField
+}
+
+func newRequestForm() *requestForm {
+	f := &requestForm{QScrollArea: widgets.NewQScrollArea(nil)}
+	f.SetWidgetResizable(true)
+	f.SetWidget(widgets.NewQLabel2("resolve a method to edit its request here", nil, 0))
+	return f
+}
+
+// Message is the request type of the form, nil before a method is resolved.
+func (f *requestForm) Message() *desc.MessageDescriptor {
+	return f.md
+}
+
+// SetMessage rebuilds the form for md, unless it already edits that type.
+func (f *requestForm) SetMessage(md *desc.MessageDescriptor) {
+	if f.md != nil && f.md.GetFullyQualifiedName() == md.GetFullyQualifiedName() {
+		return
+	}
+	f.md = md
+	f.root = newMessageField(md.GetFullyQualifiedName(), md, false)
+	f.SetWidget(f.root)
+}
+
+// JSON returns the message of the form as sent through grpcurl.
+func (f *requestForm) JSON() (string, error) {
+	if f.root == nil {
+		return "", errors.New("No request type, resolve a method first")
+	}
+	v, _, err := f.root.value()
+	if err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Failed to encode request message due to: %s", err.Error())
+	}
+	return string(b), nil
+}
+
+// SetJSON fills the form with a single json message, blank text clears it.
+func (f *requestForm) SetJSON(text string) error {
+	if f.root == nil {
+		return errors.New("No request type, resolve a method first")
+	}
+	if strings.TrimSpace(text) == "" {
+		return f.root.set(nil)
+	}
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("Failed to parse request message due to: %s", err.Error())
+	}
+	if dec.More() {
+		return errors.New("The form edits a single request message, the text holds several")
+	}
+	return f.root.set(v)
+}
+
+// formField edits the json value of a field, a list entry or a map value.
+type formField interface {
+	widgets.QWidget_ITF
+	// value returns false when the field is left out of the message.
+	value() (interface{}, bool, error)
+	// set fills the field with a decoded json value, nil clears it.
+	set(v interface{}) error
+}
+
+// fieldError locates an invalid value in the message.
+type fieldError struct {
+	path string
+	err  error
+}
+
+func (e *fieldError) Error() string {
+	return fmt.Sprintf("Invalid value of field %s: %s", e.path, e.err.Error())
+}
+
+func wrapField(name string, err error) error {
+	if fe, ok := err.(*fieldError); ok {
+		path := fe.path
+		if !strings.HasPrefix(path, "[") {
+			path = "." + path
+		}
+		return &fieldError{path: name + path, err: fe.err}
+	}
+	return &fieldError{path: name, err: err}
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a bool"
+	default:
+		return "null"
+	}
+}
+
+// newFormField picks the editor of fd, entry is set for the elements of
+// repeated fields and map values, which are never left out.
+func newFormField(fd *desc.FieldDescriptor, entry bool) formField {
+	if !entry && fd.IsMap() {
+		return newMapField(fd)
+	}
+	if !entry && fd.IsRepeated() {
+		return newListField(fd)
+	}
+	switch fd.GetType() {
+	case descpb.FieldDescriptorProto_TYPE_MESSAGE, descpb.FieldDescriptorProto_TYPE_GROUP:
+		md := fd.GetMessageType()
+		if f := newWellKnownField(md, entry); f != nil {
+			if entry {
+				return f
+			}
+			return newOptionalField(f)
+		}
+		return newMessageField(fd.GetName(), md, !entry)
+	case descpb.FieldDescriptorProto_TYPE_ENUM:
+		return newEnumField(fd.GetEnumType(), entry)
+	case descpb.FieldDescriptorProto_TYPE_BOOL:
+		return newBoolField(entry)
+	default:
+		return newTextField(fd.GetType(), entry)
+	}
+}
+
+// newWellKnownField returns the editor of the google.protobuf types with a
+// json form of their own, nil for other messages.
+func newWellKnownField(md *desc.MessageDescriptor, entry bool) formField {
+	switch name := md.GetFullyQualifiedName(); name {
+	case "google.protobuf.Timestamp":
+		return newTimestampField()
+	case "google.protobuf.Duration":
+		return newDurationField()
+	case "google.protobuf.FieldMask":
+		f := newTextField(descpb.FieldDescriptorProto_TYPE_STRING, true)
+		f.SetPlaceholderText("paths, comma separated")
+		return f
+	case "google.protobuf.Struct", "google.protobuf.Value", "google.protobuf.ListValue",
+		"google.protobuf.Any", "google.protobuf.Empty":
+		return newRawField(name)
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		// wrappers are written as the bare value, null when unset
+		return newFormField(md.FindFieldByName("value"), true)
+	}
+	return nil
+}
+
+// messageField is a group box with a row per field. Optional messages are
+// checkable and only built once checked, so recursive types do not recurse.
+type messageField struct {
+	*widgets.QGroupBox
+
+	md     *desc.MessageDescriptor
+	layout *widgets.QGridLayout
+	rows   []*formRow
+	oneofs map[string]*oneofChooser
+}
+
+type formRow struct {
+	fd     *desc.FieldDescriptor
+	label  *widgets.QLabel // nil for fields shown as a group box
+	field  formField
+	choice *oneofChooser
+}
+
+func (r *formRow) setVisible(visible bool) {
+	if r.label != nil {
+		r.label.SetVisible(visible)
+	}
+	r.field.QWidget_PTR().SetVisible(visible)
+}
+
+// oneofChooser shows the row of the chosen member of a oneof only.
+type oneofChooser struct {
+	combo   *widgets.QComboBox
+	members []*formRow
+}
+
+func (c *oneofChooser) chosen() *formRow {
+	if i := c.combo.CurrentIndex(); i > 0 {
+		return c.members[i-1]
+	}
+	return nil
+}
+
+func (c *oneofChooser) update() {
+	chosen := c.chosen()
+	for _, r := range c.members {
+		r.setVisible(r == chosen)
+	}
+}
+
+func newMessageField(title string, md *desc.MessageDescriptor, optional bool) *messageField {
+	f := &messageField{
+		QGroupBox: widgets.NewQGroupBox2(title, nil),
+		md:        md,
+		layout:    widgets.NewQGridLayout2(),
+	}
+	f.SetToolTip(md.GetFullyQualifiedName())
+	f.SetLayout(f.layout)
+	if optional {
+		f.SetCheckable(true)
+		f.SetChecked(false)
+		f.ConnectToggled(func(on bool) {
+			if on {
+				f.build()
+			}
+		})
+	} else {
+		f.build()
+	}
+	return f
+}
+
+func (f *messageField) build() {
+	if f.oneofs != nil {
+		return
+	}
+	f.oneofs = map[string]*oneofChooser{}
+	row := 0
+	for _, fd := range f.md.GetFields() {
+		r := &formRow{fd: fd, field: newFormField(fd, false)}
+		if oo := fd.GetOneOf(); oo != nil && !oo.IsSynthetic() {
+			c, ok := f.oneofs[oo.GetName()]
+			if !ok {
+				c = &oneofChooser{combo: widgets.NewQComboBox(nil)}
+				c.combo.AddItem("(none)", nil)
+				for _, choice := range oo.GetChoices() {
+					c.combo.AddItem(choice.GetName(), nil)
+				}
+				c.combo.ConnectCurrentIndexChanged(func(index int) {
+					c.update()
+				})
+				f.oneofs[oo.GetName()] = c
+				label := widgets.NewQLabel2(oo.GetName(), nil, 0)
+				label.SetToolTip("oneof")
+				f.layout.AddWidget(label, row, 0, 0)
+				f.layout.AddWidget(c.combo, row, 1, 0)
+				row++
+			}
+			c.members = append(c.members, r)
+			r.choice = c
+		}
+		if _, box := r.field.(*messageField); box || fd.IsRepeated() {
+			f.layout.AddWidget3(r.field, row, 0, 1, 2, 0)
+		} else {
+			r.label = widgets.NewQLabel2(fd.GetName(), nil, 0)
+			r.label.SetToolTip(fieldType(fd))
+			f.layout.AddWidget(r.label, row, 0, 0)
+			f.layout.AddWidget(r.field, row, 1, 0)
+		}
+		row++
+		f.rows = append(f.rows, r)
+	}
+	for _, c := range f.oneofs {
+		c.update()
+	}
+}
+
+func (f *messageField) value() (interface{}, bool, error) {
+	if f.IsCheckable() && !f.IsChecked() {
+		return nil, false, nil
+	}
+	f.build()
+	res := session.JSONObject{}
+	for _, r := range f.rows {
+		if r.choice != nil && r.choice.chosen() != r {
+			continue
+		}
+		v, ok, err := r.field.value()
+		if err != nil {
+			return nil, false, wrapField(r.fd.GetName(), err)
+		}
+		if ok {
+			res = append(res, session.JSONMember{Key: r.fd.GetJSONName(), Value: v})
+		}
+	}
+	return res, true, nil
+}
+
+func (f *messageField) set(v interface{}) error {
+	if v == nil && f.IsCheckable() {
+		f.SetChecked(false)
+		return nil
+	}
+	fields := map[string]interface{}{}
+	if v != nil {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("Expected an object for %s, got %s", f.md.GetFullyQualifiedName(), jsonType(v))
+		}
+		for k, val := range m {
+			fd := f.md.FindFieldByJSONName(k)
+			if fd == nil {
+				fd = f.md.FindFieldByName(k)
+			}
+			if fd == nil {
+				return fmt.Errorf("Message %s has no field %q", f.md.GetFullyQualifiedName(), k)
+			}
+			fields[fd.GetName()] = val
+		}
+		if f.IsCheckable() {
+			f.SetChecked(true)
+		}
+	}
+	f.build()
+	for _, c := range f.oneofs {
+		c.combo.SetCurrentIndex(0)
+	}
+	for _, r := range f.rows {
+		val, ok := fields[r.fd.GetName()]
+		if err := r.field.set(val); err != nil {
+			return wrapField(r.fd.GetName(), err)
+		}
+		if ok && r.choice != nil {
+			for i, m := range r.choice.members {
+				if m == r {
+					r.choice.combo.SetCurrentIndex(i + 1)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fieldType is the type of fd as written in a .proto file.
+func fieldType(fd *desc.FieldDescriptor) string {
+	if fd.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldType(fd.GetMapKeyType()), fieldType(fd.GetMapValueType()))
+	}
+	var name string
+	switch {
+	case fd.GetMessageType() != nil:
+		name = fd.GetMessageType().GetFullyQualifiedName()
+	case fd.GetEnumType() != nil:
+		name = fd.GetEnumType().GetFullyQualifiedName()
+	default:
+		name = strings.ToLower(strings.TrimPrefix(fd.GetType().String(), "TYPE_"))
+	}
+	if fd.IsRepeated() {
+		name = "repeated " + name
+	}
+	return name
+}
+
+// listField is a repeated field, entries are added and removed with buttons.
+type listField struct {
+	*widgets.QGroupBox
+
+	entries  *widgets.QVBoxLayout
+	rows     []*listRow
+	newEntry func() formField
+}
+
+type listRow struct {
+	*widgets.QWidget
+	field formField
+}
+
+func newListField(fd *desc.FieldDescriptor) *listField {
+	f := &listField{
+		QGroupBox: widgets.NewQGroupBox2(fd.GetName(), nil),
+		entries:   widgets.NewQVBoxLayout(),
+		newEntry: func() formField {
+			return newFormField(fd, true)
+		},
+	}
+	f.SetToolTip(fieldType(fd))
+	addButton := widgets.NewQPushButton2("+", nil)
+	addButton.ConnectClicked(func(checked bool) {
+		f.add()
+	})
+	layout := widgets.NewQVBoxLayout()
+	layout.AddLayout(f.entries, 0)
+	layout.AddWidget(addButton, 0, core.Qt__AlignLeft)
+	f.SetLayout(layout)
+	return f
+}
+
+// add appends an entry, a remove button next to it deletes it again.
+func (f *listField) add() *listRow {
+	r := &listRow{QWidget: widgets.NewQWidget(nil, 0), field: f.newEntry()}
+	removeButton := widgets.NewQPushButton2("-", nil)
+	removeButton.ConnectClicked(func(checked bool) {
+		f.remove(r)
+	})
+	layout := widgets.NewQHBoxLayout()
+	layout.AddWidget(r.field, 1, 0)
+	layout.AddWidget(removeButton, 0, core.Qt__AlignRight)
+	r.SetLayout(layout)
+	f.entries.AddWidget(r, 0, 0)
+	f.rows = append(f.rows, r)
+	return r
+}
+
+func (f *listField) remove(r *listRow) {
+	for i, row := range f.rows {
+		if row == r {
+			f.rows = append(f.rows[:i], f.rows[i+1:]...)
+			r.DeleteLater()
+			return
+		}
+	}
+}
+
+func (f *listField) clear() {
+	for _, r := range f.rows {
+		r.DeleteLater()
+	}
+	f.rows = nil
+}
+
+func (f *listField) value() (interface{}, bool, error) {
+	if len(f.rows) == 0 {
+		return nil, false, nil
+	}
+	res := make([]interface{}, 0, len(f.rows))
+	for i, r := range f.rows {
+		v, _, err := r.field.value()
+		if err != nil {
+			return nil, false, wrapField(fmt.Sprintf("[%d]", i), err)
+		}
+		res = append(res, v)
+	}
+	return res, true, nil
+}
+
+func (f *listField) set(v interface{}) error {
+	f.clear()
+	if v == nil {
+		return nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("Expected an array, got %s", jsonType(v))
+	}
+	for i, item := range items {
+		if err := f.add().field.set(item); err != nil {
+			return wrapField(fmt.Sprintf("[%d]", i), err)
+		}
+	}
+	return nil
+}
+
+// mapField is a listField whose entries are a key and a value.
+type mapField struct {
+	*listField
+}
+
+type mapEntry struct {
+	*widgets.QWidget
+	key, val formField
+}
+
+func newMapField(fd *desc.FieldDescriptor) *mapField {
+	f := &mapField{listField: newListField(fd)}
+	f.newEntry = func() formField {
+		e := &mapEntry{
+			QWidget: widgets.NewQWidget(nil, 0),
+			key:     newFormField(fd.GetMapKeyType(), true),
+			val:     newFormField(fd.GetMapValueType(), true),
+		}
+		layout := widgets.NewQHBoxLayout()
+		layout.AddWidget(e.key, 1, 0)
+		layout.AddWidget(e.val, 2, 0)
+		e.SetLayout(layout)
+		return e
+	}
+	return f
+}
+
+// value and set of a single entry are never used, the map reads both halves.
+func (e *mapEntry) value() (interface{}, bool, error) {
+	return nil, false, nil
+}
+
+func (e *mapEntry) set(v interface{}) error {
+	return nil
+}
+
+func (f *mapField) value() (interface{}, bool, error) {
+	if len(f.rows) == 0 {
+		return nil, false, nil
+	}
+	res := session.JSONObject{}
+	seen := map[string]bool{}
+	for i, r := range f.rows {
+		e := r.field.(*mapEntry)
+		k, _, err := e.key.value()
+		if err != nil {
+			return nil, false, wrapField(fmt.Sprintf("[%d]", i), err)
+		}
+		key := fmt.Sprint(k)
+		if seen[key] {
+			return nil, false, wrapField(fmt.Sprintf("[%d]", i), fmt.Errorf("duplicate key %q", key))
+		}
+		seen[key] = true
+		v, _, err := e.val.value()
+		if err != nil {
+			return nil, false, wrapField(fmt.Sprintf("[%s]", key), err)
+		}
+		res = append(res, session.JSONMember{Key: key, Value: v})
+	}
+	return res, true, nil
+}
+
+func (f *mapField) set(v interface{}) error {
+	f.clear()
+	if v == nil {
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Expected an object, got %s", jsonType(v))
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e := f.add().field.(*mapEntry)
+		var key interface{} = k
+		if _, isBool := e.key.(*boolField); isBool {
+			b, err := strconv.ParseBool(k)
+			if err != nil {
+				return wrapField(fmt.Sprintf("[%s]", k), err)
+			}
+			key = b
+		}
+		if err := e.key.set(key); err != nil {
+			return wrapField(fmt.Sprintf("[%s]", k), err)
+		}
+		if err := e.val.set(m[k]); err != nil {
+			return wrapField(fmt.Sprintf("[%s]", k), err)
+		}
+	}
+	return nil
+}
+
+// textField edits strings, bytes and numbers. Unless required, a blank field
+// is left out of the message.
+type textField struct {
+	*widgets.QLineEdit
+
+	kind     descpb.FieldDescriptorProto_Type
+	required bool
+}
+
+func newTextField(kind descpb.FieldDescriptorProto_Type, required bool) *textField {
+	f := &textField{QLineEdit: widgets.NewQLineEdit2("", nil), kind: kind, required: required}
+	switch kind {
+	case descpb.FieldDescriptorProto_TYPE_STRING:
+	case descpb.FieldDescriptorProto_TYPE_BYTES:
+		f.SetPlaceholderText("base64")
+	default:
+		f.SetPlaceholderText("0")
+	}
+	return f
+}
+
+func (f *textField) value() (interface{}, bool, error) {
+	text := f.Text()
+	if f.kind != descpb.FieldDescriptorProto_TYPE_STRING {
+		text = strings.TrimSpace(text)
+	}
+	if text == "" {
+		if !f.required {
+			return nil, false, nil
+		}
+		if f.kind != descpb.FieldDescriptorProto_TYPE_STRING && f.kind != descpb.FieldDescriptorProto_TYPE_BYTES {
+			text = "0"
+		}
+	}
+	var err error
+	switch f.kind {
+	case descpb.FieldDescriptorProto_TYPE_STRING:
+		return text, true, nil
+	case descpb.FieldDescriptorProto_TYPE_BYTES:
+		if _, err = base64.StdEncoding.DecodeString(text); err != nil {
+			_, err = base64.URLEncoding.DecodeString(text)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("%q is not base64 encoded", text)
+		}
+		return text, true, nil
+	case descpb.FieldDescriptorProto_TYPE_INT32, descpb.FieldDescriptorProto_TYPE_SINT32, descpb.FieldDescriptorProto_TYPE_SFIXED32:
+		_, err = strconv.ParseInt(text, 10, 32)
+	case descpb.FieldDescriptorProto_TYPE_UINT32, descpb.FieldDescriptorProto_TYPE_FIXED32:
+		_, err = strconv.ParseUint(text, 10, 32)
+	case descpb.FieldDescriptorProto_TYPE_INT64, descpb.FieldDescriptorProto_TYPE_SINT64, descpb.FieldDescriptorProto_TYPE_SFIXED64:
+		if _, err = strconv.ParseInt(text, 10, 64); err == nil {
+			// 64 bit integers are strings in json
+			return text, true, nil
+		}
+	case descpb.FieldDescriptorProto_TYPE_UINT64, descpb.FieldDescriptorProto_TYPE_FIXED64:
+		if _, err = strconv.ParseUint(text, 10, 64); err == nil {
+			return text, true, nil
+		}
+	case descpb.FieldDescriptorProto_TYPE_FLOAT, descpb.FieldDescriptorProto_TYPE_DOUBLE:
+		switch text {
+		case "NaN", "Infinity", "-Infinity":
+			return text, true, nil
+		}
+		bits := 64
+		if f.kind == descpb.FieldDescriptorProto_TYPE_FLOAT {
+			bits = 32
+		}
+		_, err = strconv.ParseFloat(text, bits)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("%q is not a valid %s", text, strings.ToLower(strings.TrimPrefix(f.kind.String(), "TYPE_")))
+	}
+	return json.Number(text), true, nil
+}
+
+func (f *textField) set(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		f.SetText("")
+	case string:
+		f.SetText(v)
+	case json.Number:
+		if f.kind == descpb.FieldDescriptorProto_TYPE_STRING || f.kind == descpb.FieldDescriptorProto_TYPE_BYTES {
+			return fmt.Errorf("Expected a string, got %s", jsonType(v))
+		}
+		f.SetText(v.String())
+	default:
+		return fmt.Errorf("Expected a string or number, got %s", jsonType(v))
+	}
+	return nil
+}
+
+// boolField is a check box, unless required an unchecked box is left out.
+type boolField struct {
+	*widgets.QCheckBox
+
+	required bool
+}
+
+func newBoolField(required bool) *boolField {
+	return &boolField{QCheckBox: widgets.NewQCheckBox2("", nil), required: required}
+}
+
+func (f *boolField) value() (interface{}, bool, error) {
+	on := f.IsChecked()
+	return on, on || f.required, nil
+}
+
+func (f *boolField) set(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		f.SetChecked(false)
+	case bool:
+		f.SetChecked(v)
+	default:
+		return fmt.Errorf("Expected a bool, got %s", jsonType(v))
+	}
+	return nil
+}
+
+// enumField offers the values of an enum by name. Unless required, the first
+// value, which is the default, is left out.
+type enumField struct {
+	*widgets.QComboBox
+
+	ed       *desc.EnumDescriptor
+	required bool
+}
+
+func newEnumField(ed *desc.EnumDescriptor, required bool) *enumField {
+	f := &enumField{QComboBox: widgets.NewQComboBox(nil), ed: ed, required: required}
+	for _, v := range ed.GetValues() {
+		f.AddItem(v.GetName(), nil)
+	}
+	f.SetToolTip(ed.GetFullyQualifiedName())
+	return f
+}
+
+func (f *enumField) value() (interface{}, bool, error) {
+	return f.CurrentText(), f.required || f.CurrentIndex() > 0, nil
+}
+
+func (f *enumField) set(v interface{}) error {
+	name := ""
+	switch v := v.(type) {
+	case nil:
+		f.SetCurrentIndex(0)
+		return nil
+	case string:
+		name = v
+	case json.Number:
+		n, err := strconv.ParseInt(v.String(), 10, 32)
+		if err != nil {
+			return err
+		}
+		ev := f.ed.FindValueByNumber(int32(n))
+		if ev == nil {
+			return fmt.Errorf("Enum %s has no value %d", f.ed.GetFullyQualifiedName(), n)
+		}
+		name = ev.GetName()
+	default:
+		return fmt.Errorf("Expected an enum name or number, got %s", jsonType(v))
+	}
+	for i, ev := range f.ed.GetValues() {
+		if ev.GetName() == name {
+			f.SetCurrentIndex(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Enum %s has no value %q", f.ed.GetFullyQualifiedName(), name)
+}
+
+// timestampField edits a google.protobuf.Timestamp in its json form, an RFC
+// 3339 date kept as written, nanoseconds included.
+type timestampField struct {
+	*widgets.QLineEdit
+}
+
+func newTimestampField() *timestampField {
+	f := &timestampField{QLineEdit: widgets.NewQLineEdit2(time.Now().UTC().Format(time.RFC3339), nil)}
+	f.SetPlaceholderText("1970-01-01T00:00:00.000000001Z")
+	return f
+}
+
+func (f *timestampField) value() (interface{}, bool, error) {
+	text := strings.TrimSpace(f.Text())
+	if err := checkTimestamp(text); err != nil {
+		return nil, false, fmt.Errorf("%q is not an RFC 3339 timestamp like \"1970-01-01T00:00:00Z\"", text)
+	}
+	return text, true, nil
+}
+
+func (f *timestampField) set(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		f.SetText(time.Now().UTC().Format(time.RFC3339))
+	case string:
+		if err := checkTimestamp(v); err != nil {
+			return fmt.Errorf("%q is not an RFC 3339 timestamp like \"1970-01-01T00:00:00Z\"", v)
+		}
+		f.SetText(v)
+	default:
+		return fmt.Errorf("Expected an RFC 3339 timestamp, got %s", jsonType(v))
+	}
+	return nil
+}
+
+// durationField edits a google.protobuf.Duration in its json form, seconds
+// kept as written, nanoseconds included.
+type durationField struct {
+	*widgets.QLineEdit
+}
+
+func newDurationField() *durationField {
+	f := &durationField{QLineEdit: widgets.NewQLineEdit2("0s", nil)}
+	f.SetPlaceholderText("1.000000001s")
+	return f
+}
+
+func (f *durationField) value() (interface{}, bool, error) {
+	text := strings.TrimSpace(f.Text())
+	if err := checkDuration(text); err != nil {
+		return nil, false, fmt.Errorf("%q is not a duration like \"1.5s\"", text)
+	}
+	return text, true, nil
+}
+
+func (f *durationField) set(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		f.SetText("0s")
+	case string:
+		if err := checkDuration(v); err != nil {
+			return fmt.Errorf("%q is not a duration like \"1.5s\"", v)
+		}
+		f.SetText(v)
+	default:
+		return fmt.Errorf("Expected a duration like \"1.5s\", got %s", jsonType(v))
+	}
+	return nil
+}
+
+// checkTimestamp parses text as the json form of a Timestamp, in range.
+func checkTimestamp(text string) error {
+	var ts tspb.Timestamp
+	if err := jsonpb.UnmarshalString(strconv.Quote(text), &ts); err != nil {
+		return err
+	}
+	_, err := ptypes.Timestamp(&ts)
+	return err
+}
+
+// checkDuration parses text as the json form of a Duration, in range.
+func checkDuration(text string) error {
+	var d durpb.Duration
+	if err := jsonpb.UnmarshalString(strconv.Quote(text), &d); err != nil {
+		return err
+	}
+	_, err := ptypes.Duration(&d)
+	return err
+}
+
+// rawField takes the json of the types without fields of their own, like
+// google.protobuf.Struct or Any.
+type rawField struct {
+	*widgets.QLineEdit
+}
+
+func newRawField(typeName string) *rawField {
+	f := &rawField{QLineEdit: widgets.NewQLineEdit2("", nil)}
+	switch typeName {
+	case "google.protobuf.Any":
+		f.SetPlaceholderText(`{"@type": "type.googleapis.com/package.Message", ...}`)
+	case "google.protobuf.Empty":
+		f.SetText("{}")
+	default:
+		f.SetPlaceholderText("json")
+	}
+	return f
+}
+
+func (f *rawField) value() (interface{}, bool, error) {
+	text := strings.TrimSpace(f.Text())
+	if text == "" {
+		text = "null"
+	}
+	if !json.Valid([]byte(text)) {
+		return nil, false, fmt.Errorf("%q is not valid json", text)
+	}
+	return json.RawMessage(text), true, nil
+}
+
+func (f *rawField) set(v interface{}) error {
+	if v == nil {
+		f.SetText("")
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f.SetText(string(b))
+	return nil
+}
+
+// optionalField puts a check box before the editor of a singular well-known
+// type, the field is left out unless it is checked.
+type optionalField struct {
+	*widgets.QWidget
+
+	box   *widgets.QCheckBox
+	inner formField
+}
+
+func newOptionalField(inner formField) *optionalField {
+	f := &optionalField{
+		QWidget: widgets.NewQWidget(nil, 0),
+		box:     widgets.NewQCheckBox2("", nil),
+		inner:   inner,
+	}
+	inner.QWidget_PTR().SetDisabled(true)
+	f.box.ConnectClicked(func(checked bool) {
+		inner.QWidget_PTR().SetDisabled(!f.box.IsChecked())
+	})
+	layout := widgets.NewQHBoxLayout()
+	layout.AddWidget(f.box, 0, 0)
+	layout.AddWidget(inner, 1, 0)
+	f.SetLayout(layout)
+	return f
+}
+
+func (f *optionalField) value() (interface{}, bool, error) {
+	if !f.box.IsChecked() {
+		return nil, false, nil
+	}
+	return f.inner.value()
+}
+
+func (f *optionalField) set(v interface{}) error {
+	f.box.SetChecked(v != nil)
+	f.inner.QWidget_PTR().SetDisabled(v == nil)
+	return f.inner.set(v)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/RoomOfRequirement/qt_grpc/session"
+	"github.com/bojand/ghz/runner"
+	"github.com/therecipe/qt/widgets"
+)
+
+// resultsView shows a load test report as tables instead of the ghz text
+// summary, counts and rates being sized by a progress bar in their row.
+type resultsView struct {
+	*widgets.QWidget
+
+	report  *runner.Report
+	tabs    *widgets.QTabWidget
+	verdict *widgets.QLabel
+
+	// the report is saved by the owner, which knows where to show errors
+	format     *widgets.QComboBox
+	saveButton *widgets.QPushButton
+
+	summary     *widgets.QTableWidget
+	percentiles *widgets.QTableWidget
+	histogram   *widgets.QTableWidget
+	statuses    *widgets.QTableWidget
+	errors      *widgets.QTableWidget
+	rps         *widgets.QTableWidget
+	stages      *widgets.QTableWidget
+	assertions  *widgets.QTableWidget
+}
+
+func newResultsView() *resultsView {
+	v := &resultsView{QWidget: widgets.NewQWidget(nil, 0)}
+	v.tabs = widgets.NewQTabWidget(nil)
+	v.summary = newReadOnlyTable("", "value")
+	v.summary.HorizontalHeader().SetVisible(false)
+	v.percentiles = newReadOnlyTable("percentile", "latency")
+	v.histogram = newReadOnlyTable("latency", "count", "")
+	v.statuses = newReadOnlyTable("status", "count")
+	v.errors = newReadOnlyTable("error", "count")
+	v.rps = newReadOnlyTable("since start", "rps", "")
+	v.assertions = newReadOnlyTable("assertion", "actual", "result")
+	v.verdict = widgets.NewQLabel2("", nil, 0)
+	v.verdict.Hide()
+	v.stages = newReadOnlyTable("since start", "scheduled", "count", "rps", "errors", "average", "p50", "p90", "p99", "")
+
+	latency := widgets.NewQWidget(nil, 0)
+	latencyLayout := widgets.NewQHBoxLayout()
+	latencyLayout.AddWidget(v.histogram, 2, 0)
+	latencyLayout.AddWidget(v.percentiles, 1, 0)
+	latency.SetLayout(latencyLayout)
+
+	statuses := widgets.NewQWidget(nil, 0)
+	statusLayout := widgets.NewQHBoxLayout()
+	statusLayout.AddWidget(v.statuses, 1, 0)
+	statusLayout.AddWidget(v.errors, 2, 0)
+	statuses.SetLayout(statusLayout)
+
+	v.tabs.AddTab(v.summary, "summary")
+	v.tabs.AddTab(latency, "latency")
+	v.tabs.AddTab(statuses, "status codes")
+	v.tabs.AddTab(v.rps, "rps over time")
+	v.tabs.AddTab(v.stages, "stages")
+	v.tabs.AddTab(v.assertions, "assertions")
+
+	v.format = widgets.NewQComboBox(nil)
+	v.format.AddItems(session.ReportFormats)
+	v.format.SetCurrentText("json")
+	v.saveButton = widgets.NewQPushButton2("save report", nil)
+	v.saveButton.SetDisabled(true)
+	saveLayout := widgets.NewQHBoxLayout()
+	saveLayout.AddStretch(1)
+	saveLayout.AddWidget(v.format, 0, 0)
+	saveLayout.AddWidget(v.saveButton, 0, 0)
+
+	layout := widgets.NewQVBoxLayout()
+	layout.AddWidget(v.verdict, 0, 0)
+	layout.AddWidget(v.tabs, 1, 0)
+	layout.AddLayout(saveLayout, 0)
+	v.SetLayout(layout)
+	return v
+}
+
+func newReadOnlyTable(labels ...string) *widgets.QTableWidget {
+	t := widgets.NewQTableWidget2(0, len(labels), nil)
+	t.SetHorizontalHeaderLabels(labels)
+	t.HorizontalHeader().SetStretchLastSection(true)
+	t.VerticalHeader().SetVisible(false)
+	t.SetEditTriggers(widgets.QAbstractItemView__NoEditTriggers)
+	return t
+}
+
+// SetReport shows r, split by the stages of the load profile it ran with.
+func (v *resultsView) SetReport(r *runner.Report, profile session.LoadProfile) {
+	v.report = r
+	v.saveButton.SetDisabled(false)
+	setRows(v.summary, [][]string{
+		{"name", r.Name},
+		{"end reason", r.EndReason.String()},
+		{"count", fmt.Sprint(r.Count)},
+		{"total", r.Total.String()},
+		{"slowest", r.Slowest.String()},
+		{"fastest", r.Fastest.String()},
+		{"average", r.Average.String()},
+		{"requests/sec", fmt.Sprintf("%.2f", r.Rps)},
+	})
+
+	var rows [][]string
+	for _, l := range r.LatencyDistribution {
+		rows = append(rows, []string{fmt.Sprintf("p%d", l.Percentage), l.Latency.String()})
+	}
+	setRows(v.percentiles, rows)
+
+	rows = nil
+	var counts []float64
+	for _, b := range r.Histogram {
+		mark := time.Duration(b.Mark * float64(time.Second))
+		rows = append(rows, []string{mark.String(), fmt.Sprint(b.Count)})
+		counts = append(counts, float64(b.Count))
+	}
+	setRows(v.histogram, rows)
+	setBars(v.histogram, 2, counts, func(i int) string {
+		return fmt.Sprintf("%.2f%%", r.Histogram[i].Frequency*100)
+	})
+
+	setRows(v.statuses, countRows(r.StatusCodeDist))
+	setRows(v.errors, countRows(r.ErrorDist))
+
+	rows = nil
+	counts = nil
+	points := session.RPSOverTime(r, time.Second)
+	for _, p := range points {
+		rows = append(rows, []string{p.Start.String(), fmt.Sprintf("%.1f", p.RPS)})
+		counts = append(counts, p.RPS)
+	}
+	setRows(v.rps, rows)
+	setBars(v.rps, 2, counts, func(int) string { return "" })
+
+	rows = nil
+	counts = nil
+	for _, st := range profile.Stages(r) {
+		level := ""
+		if st.Level != 0 {
+			level = fmt.Sprintf("%.0f", st.Level)
+		}
+		row := []string{
+			st.Start.String(),
+			level,
+			fmt.Sprint(st.Count),
+			fmt.Sprintf("%.1f", st.RPS),
+			fmt.Sprint(st.Errors),
+			st.Average.String(),
+		}
+		for _, l := range st.Latency {
+			row = append(row, l.Latency.String())
+		}
+		rows = append(rows, row)
+		counts = append(counts, st.RPS)
+	}
+	setRows(v.stages, rows)
+	setBars(v.stages, 9, counts, func(int) string { return "" })
+}
+
+// SetAssertions shows results of the assertions checked against the report,
+// the verdict is hidden when there are none.
+func (v *resultsView) SetAssertions(results []session.AssertionResult) {
+	rows := make([][]string, len(results))
+	failed := 0
+	for i, r := range results {
+		verdict := "pass"
+		if !r.Pass {
+			verdict = "FAIL"
+			failed++
+		}
+		rows[i] = []string{r.Text, r.ActualText(), verdict}
+	}
+	setRows(v.assertions, rows)
+
+	switch {
+	case len(results) == 0:
+		v.verdict.Hide()
+		return
+	case failed == 0:
+		v.verdict.SetText(fmt.Sprintf("all %d assertions passed", len(results)))
+		v.verdict.SetStyleSheet("color: green")
+	default:
+		v.verdict.SetText(fmt.Sprintf("%d of %d assertions failed", failed, len(results)))
+		v.verdict.SetStyleSheet("color: red")
+	}
+	v.verdict.Show()
+}
+
+// Format is the ghz printer format picked for saving.
+func (v *resultsView) Format() string {
+	return v.format.CurrentText()
+}
+
+// reportFilter is the file dialog filter of a ghz printer format.
+func reportFilter(format string) string {
+	switch format {
+	case "json", "pretty":
+		return "JSON (*.json)"
+	case "csv":
+		return "CSV (*.csv)"
+	case "html":
+		return "HTML (*.html)"
+	default:
+		return "Text (*.txt);;All files (*)"
+	}
+}
+
+// countRows sorts a ghz distribution by count, most frequent first.
+func countRows(dist map[string]int) [][]string {
+	keys := make([]string, 0, len(dist))
+	for k := range dist {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if dist[keys[i]] != dist[keys[j]] {
+			return dist[keys[i]] > dist[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	rows := make([][]string, len(keys))
+	for i, k := range keys {
+		rows[i] = []string{k, fmt.Sprint(dist[k])}
+	}
+	return rows
+}
+
+func setRows(t *widgets.QTableWidget, rows [][]string) {
+	t.SetRowCount(len(rows))
+	for i, row := range rows {
+		for j, cell := range row {
+			t.SetItem(i, j, widgets.NewQTableWidgetItem2(cell, 0))
+		}
+	}
+	t.ResizeColumnsToContents()
+}
+
+// setBars puts a progress bar per value in column col, filled relative to
+// the largest value.
+func setBars(t *widgets.QTableWidget, col int, values []float64, label func(i int) string) {
+	max := 0.0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	for i, v := range values {
+		bar := widgets.NewQProgressBar(nil)
+		bar.SetRange(0, 1000)
+		if max > 0 {
+			bar.SetValue(int(v / max * 1000))
+		}
+		bar.SetFormat(label(i))
+		t.SetCellWidget(i, col, bar)
+	}
+}
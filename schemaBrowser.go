@@ -0,0 +1,384 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/RoomOfRequirement/qt_grpc/session"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/widgets"
+)
+
+// schemaBrowser shows the files of the schema as a tree of packages, their
+// services, methods, messages and enums, and the files themselves. The
+// details of the selected node link to the types they use.
+type schemaBrowser struct {
+	*widgets.QWidget
+
+	filter  *widgets.QLineEdit
+	tree    *widgets.QTreeWidget
+	details *widgets.QTextBrowser
+
+	roots []*schemaNode
+	// by fully qualified name, files by "file:" and packages by "package:"
+	nodes map[string]*schemaNode
+
+	// called with the fully qualified name of a method once it is selected
+	onMethod func(name string)
+}
+
+type schemaNode struct {
+	item     *widgets.QTreeWidgetItem
+	key      string
+	label    string
+	dsc      desc.Descriptor // nil for packages and the group of files
+	parent   *schemaNode
+	children []*schemaNode
+}
+
+func newSchemaBrowser() *schemaBrowser {
+	b := &schemaBrowser{
+		QWidget: widgets.NewQWidget(nil, 0),
+		filter:  widgets.NewQLineEdit2("", nil),
+		tree:    widgets.NewQTreeWidget(nil),
+		details: widgets.NewQTextBrowser(nil),
+		nodes:   map[string]*schemaNode{},
+	}
+	b.filter.SetPlaceholderText("search")
+	// the third column holds the key of the node
+	b.tree.SetColumnCount(3)
+	b.tree.SetHeaderLabels([]string{"name", "kind", "key"})
+	b.tree.SetColumnHidden(2, true)
+	b.details.SetReadOnly(true)
+	b.details.SetOpenLinks(false)
+
+	layout := widgets.NewQVBoxLayout()
+	layout.AddWidget(b.filter, 0, 0)
+	layout.AddWidget(b.tree, 2, 0)
+	layout.AddWidget(b.details, 1, 0)
+	b.SetLayout(layout)
+
+	b.filter.ConnectTextChanged(func(text string) {
+		b.applyFilter(text)
+	})
+
+	b.tree.ConnectCurrentItemChanged(func(current, previous *widgets.QTreeWidgetItem) {
+		if current == nil {
+			return
+		}
+		n, ok := b.nodes[current.Text(2)]
+		if !ok {
+			return
+		}
+		b.showDetails(n)
+		if md, ok := n.dsc.(*desc.MethodDescriptor); ok && b.onMethod != nil {
+			b.onMethod(md.GetFullyQualifiedName())
+		}
+	})
+
+	b.details.ConnectAnchorClicked(func(link *core.QUrl) {
+		b.jump(link.ToString(core.QUrl__None))
+	})
+
+	return b
+}
+
+// Clear empties the tree, e.g. once the schema is refreshed.
+func (b *schemaBrowser) Clear() {
+	b.tree.Clear()
+	b.details.Clear()
+	b.roots = nil
+	b.nodes = map[string]*schemaNode{}
+}
+
+// SetFiles fills the tree with everything declared in files.
+func (b *schemaBrowser) SetFiles(files []*desc.FileDescriptor) {
+	b.Clear()
+	files = append([]*desc.FileDescriptor(nil), files...)
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].GetName() < files[j].GetName()
+	})
+
+	var names []string
+	pkgs := map[string]*schemaNode{}
+	for _, fd := range files {
+		if _, ok := pkgs[fd.GetPackage()]; !ok {
+			pkgs[fd.GetPackage()] = nil
+			names = append(names, fd.GetPackage())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		label := name
+		if label == "" {
+			label = "(no package)"
+		}
+		pkgs[name] = b.add(nil, "package:"+name, label, "package", nil)
+	}
+
+	// services first, then messages and enums, whichever file they are in
+	for _, fd := range files {
+		for _, sd := range fd.GetServices() {
+			svc := b.add(pkgs[fd.GetPackage()], sd.GetFullyQualifiedName(), sd.GetName(), "service", sd)
+			for _, md := range sd.GetMethods() {
+				b.add(svc, md.GetFullyQualifiedName(), md.GetName(), "rpc", md)
+			}
+		}
+	}
+	for _, fd := range files {
+		for _, md := range fd.GetMessageTypes() {
+			b.addMessage(pkgs[fd.GetPackage()], md)
+		}
+	}
+	for _, fd := range files {
+		for _, ed := range fd.GetEnumTypes() {
+			b.add(pkgs[fd.GetPackage()], ed.GetFullyQualifiedName(), ed.GetName(), "enum", ed)
+		}
+	}
+
+	group := b.add(nil, "files", "files", "", nil)
+	for _, fd := range files {
+		b.add(group, "file:"+fd.GetName(), fd.GetName(), "file", fd)
+	}
+	b.applyFilter(b.filter.Text())
+}
+
+func (b *schemaBrowser) add(parent *schemaNode, key, label, kind string, dsc desc.Descriptor) *schemaNode {
+	n := &schemaNode{
+		item:   widgets.NewQTreeWidgetItem2([]string{label, kind, key}, 0),
+		key:    key,
+		label:  label,
+		dsc:    dsc,
+		parent: parent,
+	}
+	if dsc != nil {
+		n.item.SetToolTip(0, dsc.GetFullyQualifiedName())
+	}
+	if parent == nil {
+		b.tree.AddTopLevelItem(n.item)
+		b.roots = append(b.roots, n)
+	} else {
+		parent.item.AddChild(n.item)
+		parent.children = append(parent.children, n)
+	}
+	b.nodes[key] = n
+	return n
+}
+
+// addMessage adds md with its nested messages and enums below it, leaving
+// out the entries of map fields.
+func (b *schemaBrowser) addMessage(parent *schemaNode, md *desc.MessageDescriptor) {
+	n := b.add(parent, md.GetFullyQualifiedName(), md.GetName(), "message", md)
+	for _, nested := range md.GetNestedMessageTypes() {
+		if !nested.IsMapEntry() {
+			b.addMessage(n, nested)
+		}
+	}
+	for _, ed := range md.GetNestedEnumTypes() {
+		b.add(n, ed.GetFullyQualifiedName(), ed.GetName(), "enum", ed)
+	}
+}
+
+// applyFilter hides the nodes whose name does not contain text, unless a
+// node below them does.
+func (b *schemaBrowser) applyFilter(text string) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	for _, n := range b.roots {
+		b.filterNode(n, text)
+	}
+}
+
+func (b *schemaBrowser) filterNode(n *schemaNode, text string) bool {
+	visible := text == "" || strings.Contains(strings.ToLower(n.label), text) ||
+		(n.dsc != nil && strings.Contains(strings.ToLower(n.key), text))
+	childVisible := false
+	for _, c := range n.children {
+		if b.filterNode(c, text) {
+			childVisible = true
+		}
+	}
+	if text != "" {
+		n.item.SetExpanded(childVisible)
+	}
+	n.item.SetHidden(!visible && !childVisible)
+	return visible || childVisible
+}
+
+// jump selects the node a link of the details points to.
+func (b *schemaBrowser) jump(link string) {
+	key := strings.TrimPrefix(link, "type:")
+	n, ok := b.nodes[key]
+	if !ok {
+		return
+	}
+	if b.filter.Text() != "" {
+		b.filter.SetText("")
+	}
+	for p := n.parent; p != nil; p = p.parent {
+		p.item.SetExpanded(true)
+	}
+	b.tree.SetCurrentItem(n.item)
+	b.tree.ScrollToItem(n.item, widgets.QAbstractItemView__PositionAtCenter)
+}
+
+func (b *schemaBrowser) showDetails(n *schemaNode) {
+	var res string
+	switch d := n.dsc.(type) {
+	case *desc.FileDescriptor:
+		res = fileHTML(d)
+	case *desc.ServiceDescriptor:
+		res = fmt.Sprintf("<h3>service %s</h3>%s<ul>", html.EscapeString(d.GetFullyQualifiedName()), declaredIn(d))
+		for _, md := range d.GetMethods() {
+			res += "<li>" + methodHTML(md) + "</li>"
+		}
+		res += "</ul>"
+	case *desc.MethodDescriptor:
+		res = fmt.Sprintf("<h3>rpc %s</h3>%s<p>%s</p><h4>types</h4>%s",
+			html.EscapeString(d.GetFullyQualifiedName()), declaredIn(d), methodHTML(d), typeTreeHTML(session.TypeTree(d)))
+	case *desc.MessageDescriptor:
+		res = fmt.Sprintf("<h3>message %s</h3>%s%s", html.EscapeString(d.GetFullyQualifiedName()), declaredIn(d), fieldsHTML(d))
+	case *desc.EnumDescriptor:
+		res = fmt.Sprintf("<h3>enum %s</h3>%s<table>", html.EscapeString(d.GetFullyQualifiedName()), declaredIn(d))
+		for _, v := range d.GetValues() {
+			res += fmt.Sprintf("<tr><td>%s</td><td>%d</td></tr>", html.EscapeString(v.GetName()), v.GetNumber())
+		}
+		res += "</table>"
+	default:
+		// packages and the group of files list what they hold
+		res = "<ul>"
+		for _, c := range n.children {
+			link := typeLink(c.dsc)
+			if fd, ok := c.dsc.(*desc.FileDescriptor); ok {
+				link = fileLink(fd)
+			}
+			res += "<li>" + c.item.Text(1) + " " + link + "</li>"
+		}
+		res += "</ul>"
+	}
+	if n.dsc != nil {
+		if _, isFile := n.dsc.(*desc.FileDescriptor); !isFile {
+			if sym, err := session.DescribeDescriptor(n.dsc); err == nil {
+				res += "<pre>" + html.EscapeString(sym.Text) + "</pre>"
+			}
+		}
+	}
+	b.details.SetHtml(res)
+}
+
+func typeLink(d desc.Descriptor) string {
+	name := html.EscapeString(d.GetFullyQualifiedName())
+	return fmt.Sprintf(`<a href="type:%s">%s</a>`, name, name)
+}
+
+func fileLink(fd *desc.FileDescriptor) string {
+	name := html.EscapeString(fd.GetName())
+	return fmt.Sprintf(`<a href="file:%s">%s</a>`, name, name)
+}
+
+func declaredIn(d desc.Descriptor) string {
+	return "<p>declared in " + fileLink(d.GetFile()) + "</p>"
+}
+
+// fieldTypeHTML is the type of fd as in a .proto file, linking to messages
+// and enums.
+func fieldTypeHTML(fd *desc.FieldDescriptor) string {
+	if fd.IsMap() {
+		return fmt.Sprintf("map&lt;%s, %s&gt;", fieldTypeHTML(fd.GetMapKeyType()), fieldTypeHTML(fd.GetMapValueType()))
+	}
+	var res string
+	switch {
+	case fd.GetMessageType() != nil:
+		res = typeLink(fd.GetMessageType())
+	case fd.GetEnumType() != nil:
+		res = typeLink(fd.GetEnumType())
+	default:
+		res = strings.ToLower(strings.TrimPrefix(fd.GetType().String(), "TYPE_"))
+	}
+	if fd.IsRepeated() {
+		res = "repeated " + res
+	}
+	return res
+}
+
+func methodHTML(md *desc.MethodDescriptor) string {
+	in, out := typeLink(md.GetInputType()), typeLink(md.GetOutputType())
+	if md.IsClientStreaming() {
+		in = "stream " + in
+	}
+	if md.IsServerStreaming() {
+		out = "stream " + out
+	}
+	name := html.EscapeString(md.GetName())
+	return fmt.Sprintf(`rpc <a href="type:%s">%s</a>(%s) returns (%s)`, html.EscapeString(md.GetFullyQualifiedName()), name, in, out)
+}
+
+func fieldsHTML(md *desc.MessageDescriptor) string {
+	res := "<table><tr><th>field</th><th>type</th><th>number</th><th>oneof</th></tr>"
+	for _, fd := range md.GetFields() {
+		oneof := ""
+		if oo := fd.GetOneOf(); oo != nil && !oo.IsSynthetic() {
+			oneof = html.EscapeString(oo.GetName())
+		}
+		res += fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%d</td><td>%s</td></tr>",
+			html.EscapeString(fd.GetName()), fieldTypeHTML(fd), fd.GetNumber(), oneof)
+	}
+	return res + "</table>"
+}
+
+// typeTreeHTML nests the types of a method as lists, see session.TypeTree.
+func typeTreeHTML(nodes []*session.TypeNode) string {
+	res := "<ul>"
+	for _, n := range nodes {
+		res += "<li>"
+		if n.Field != nil {
+			res += html.EscapeString(n.Field.GetName()) + ": " + fieldTypeHTML(n.Field)
+		} else {
+			res += typeLink(n.Descriptor)
+		}
+		if n.Cycle {
+			res += " (recursive)"
+		}
+		if len(n.Children) != 0 {
+			res += typeTreeHTML(n.Children)
+		}
+		res += "</li>"
+	}
+	return res + "</ul>"
+}
+
+func fileHTML(fd *desc.FileDescriptor) string {
+	syntax := "proto2"
+	if fd.IsProto3() {
+		syntax = "proto3"
+	}
+	res := fmt.Sprintf("<h3>%s</h3><p>package %s, %s</p>", html.EscapeString(fd.GetName()), html.EscapeString(fd.GetPackage()), syntax)
+	list := func(title string, links []string) {
+		if len(links) != 0 {
+			res += "<h4>" + title + "</h4><ul><li>" + strings.Join(links, "</li><li>") + "</li></ul>"
+		}
+	}
+	var links []string
+	for _, dep := range fd.GetDependencies() {
+		links = append(links, fileLink(dep))
+	}
+	list("imports", links)
+	links = nil
+	for _, sd := range fd.GetServices() {
+		links = append(links, typeLink(sd))
+	}
+	list("services", links)
+	links = nil
+	for _, md := range fd.GetMessageTypes() {
+		links = append(links, typeLink(md))
+	}
+	list("messages", links)
+	links = nil
+	for _, ed := range fd.GetEnumTypes() {
+		links = append(links, typeLink(ed))
+	}
+	list("enums", links)
+	return res
+}
@@ -4,13 +4,12 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	pb "github.com/RoomOfRequirement/qt_grpc/proto"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
 	"log"
 	"net"
-
-	pb "../proto"
 )
 
 var port = flag.Int("port", 10000, "the port to serve on")
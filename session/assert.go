@@ -0,0 +1,189 @@
+package session
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// Assertion is a threshold a load test report has to meet, written like
+// "p99 < 200ms", "error_rate < 0.1%" or "rps >= 1000".
+//
+// Metrics are count, errors, error_rate (percent), rps, and the latencies
+// average, fastest, slowest and p10, p25, p50, p75, p90, p95, p99.
+type Assertion struct {
+	Metric string
+	Op     string
+	Value  float64 // milliseconds for latencies
+	Text   string
+}
+
+var assertionOps = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// ParseAssertion parses a single "metric op value" threshold.
+func ParseAssertion(text string) (Assertion, error) {
+	a := Assertion{Text: strings.TrimSpace(text)}
+	pos := -1
+	for _, op := range assertionOps {
+		if i := strings.Index(a.Text, op); i > 0 {
+			pos, a.Op = i, op
+			break
+		}
+	}
+	if pos < 0 {
+		return a, fmt.Errorf("Assertion %q has no comparison, expected one of %s", text, strings.Join(assertionOps, " "))
+	}
+	a.Metric = strings.ToLower(strings.TrimSpace(a.Text[:pos]))
+	value := strings.TrimSpace(a.Text[pos+len(a.Op):])
+
+	var err error
+	switch {
+	case isLatency(a.Metric):
+		var d time.Duration
+		if d, err = time.ParseDuration(value); err == nil {
+			a.Value = float64(d) / float64(time.Millisecond)
+		}
+	case a.Metric == "error_rate":
+		a.Value, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	case a.Metric == "count" || a.Metric == "errors" || a.Metric == "rps":
+		a.Value, err = strconv.ParseFloat(value, 64)
+	case strings.HasPrefix(a.Metric, "p"):
+		return a, fmt.Errorf("Assertion %q has unknown percentile %q, ghz reports %s", text, a.Metric, strings.Join(percentiles, " "))
+	default:
+		return a, fmt.Errorf("Assertion %q has unknown metric %q", text, a.Metric)
+	}
+	if err != nil {
+		return a, fmt.Errorf("Assertion %q has invalid value: %s", text, err.Error())
+	}
+	return a, nil
+}
+
+// ParseAssertions parses one assertion per non-empty line.
+func ParseAssertions(lines []string) ([]Assertion, error) {
+	var res []Assertion
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		a, err := ParseAssertion(line)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+// percentiles are those of the latency distribution of a ghz report.
+var percentiles = []string{"p10", "p25", "p50", "p75", "p90", "p95", "p99"}
+
+func isLatency(metric string) bool {
+	switch metric {
+	case "average", "fastest", "slowest":
+		return true
+	}
+	for _, p := range percentiles {
+		if metric == p {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertionResult is an assertion checked against a report.
+type AssertionResult struct {
+	Assertion
+	Actual float64
+	Pass   bool
+	Err    error // the report lacks the metric, e.g. an unknown percentile
+}
+
+// ActualText renders the measured value in the unit of the assertion.
+func (r AssertionResult) ActualText() string {
+	switch {
+	case r.Err != nil:
+		return r.Err.Error()
+	case isLatency(r.Metric):
+		return time.Duration(r.Actual * float64(time.Millisecond)).String()
+	case r.Metric == "error_rate":
+		return fmt.Sprintf("%.3f%%", r.Actual)
+	case r.Metric == "rps":
+		return fmt.Sprintf("%.2f", r.Actual)
+	default:
+		return fmt.Sprintf("%.0f", r.Actual)
+	}
+}
+
+// Check evaluates assertions against report.
+func Check(report *runner.Report, assertions []Assertion) []AssertionResult {
+	res := make([]AssertionResult, len(assertions))
+	for i, a := range assertions {
+		r := AssertionResult{Assertion: a}
+		r.Actual, r.Err = metric(report, a.Metric)
+		r.Pass = r.Err == nil && compare(r.Actual, a.Op, a.Value)
+		res[i] = r
+	}
+	return res
+}
+
+// Passed tells whether every assertion passed.
+func Passed(results []AssertionResult) bool {
+	for _, r := range results {
+		if !r.Pass {
+			return false
+		}
+	}
+	return true
+}
+
+func metric(report *runner.Report, name string) (float64, error) {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	switch name {
+	case "count":
+		return float64(report.Count), nil
+	case "errors":
+		n := 0
+		for _, c := range report.ErrorDist {
+			n += c
+		}
+		return float64(n), nil
+	case "error_rate":
+		return ErrorRate(report), nil
+	case "rps":
+		return report.Rps, nil
+	case "average":
+		return ms(report.Average), nil
+	case "fastest":
+		return ms(report.Fastest), nil
+	case "slowest":
+		return ms(report.Slowest), nil
+	}
+	pct, _ := strconv.Atoi(strings.TrimPrefix(name, "p"))
+	for _, l := range report.LatencyDistribution {
+		if l.Percentage == pct {
+			return ms(l.Latency), nil
+		}
+	}
+	return 0, fmt.Errorf("Report has no %s latency", name)
+}
+
+func compare(actual float64, op string, value float64) bool {
+	switch op {
+	case "<":
+		return actual < value
+	case "<=":
+		return actual <= value
+	case ">":
+		return actual > value
+	case ">=":
+		return actual >= value
+	case "==":
+		return actual == value
+	case "!=":
+		return actual != value
+	}
+	return false
+}
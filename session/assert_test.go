@@ -0,0 +1,49 @@
+package session
+
+import (
+	"testing"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Assertion
+		wantErr bool
+	}{
+		{text: "p99 < 200ms", want: Assertion{Metric: "p99", Op: "<", Value: 200}},
+		{text: " P50<=1.5s ", want: Assertion{Metric: "p50", Op: "<=", Value: 1500}},
+		{text: "average != 500us", want: Assertion{Metric: "average", Op: "!=", Value: 0.5}},
+		{text: "error_rate < 0.1%", want: Assertion{Metric: "error_rate", Op: "<", Value: 0.1}},
+		{text: "error_rate == 2", want: Assertion{Metric: "error_rate", Op: "==", Value: 2}},
+		{text: "rps >= 1000", want: Assertion{Metric: "rps", Op: ">=", Value: 1000}},
+		{text: "count > 10", want: Assertion{Metric: "count", Op: ">", Value: 10}},
+		{text: "errors == 0", want: Assertion{Metric: "errors", Op: "==", Value: 0}},
+		{text: "p99 200ms", wantErr: true},
+		{text: "< 200ms", wantErr: true},
+		{text: "latency < 200ms", wantErr: true},
+		{text: "p99 < 200", wantErr: true},
+		{text: "rps > fast", wantErr: true},
+		{text: "p10 < 1ms", want: Assertion{Metric: "p10", Op: "<", Value: 1}},
+		{text: "p0 < 1ms", wantErr: true},
+		{text: "p-1 < 1ms", wantErr: true},
+		{text: "p150 < 1ms", wantErr: true},
+		{text: "p98 < 1ms", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseAssertion(tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseAssertion(%q) = %+v, want an error", tt.text, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAssertion(%q) error = %v", tt.text, err)
+			}
+			if got.Metric != tt.want.Metric || got.Op != tt.want.Op || got.Value != tt.want.Value {
+				t.Errorf("ParseAssertion(%q) = %s %s %v, want %s %s %v", tt.text, got.Metric, got.Op, got.Value, tt.want.Metric, tt.want.Op, tt.want.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// Cache keeps the schema of every target resolved through reflection, so that
+// browsing methods and messages does not hit the server again.
+//
+// When a directory is set the schema is also written there as a protoset and
+// used as a fallback whenever the server cannot be reached.
+type Cache struct {
+	mu      sync.Mutex
+	dir     string
+	entries map[string]grpcurl.DescriptorSource
+}
+
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir, entries: map[string]grpcurl.DescriptorSource{}}
+}
+
+// SetDir changes the on-disk cache directory, an empty dir disables it.
+func (c *Cache) SetDir(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dir = dir
+}
+
+// Get returns the in-memory schema of target.
+func (c *Cache) Get(target string) (grpcurl.DescriptorSource, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ds, ok := c.entries[target]
+	return ds, ok
+}
+
+// Put stores files as the schema of target and returns a descriptor source
+// backed by them.
+func (c *Cache) Put(target string, files []*desc.FileDescriptor) (grpcurl.DescriptorSource, error) {
+	ds, err := grpcurl.DescriptorSourceFromFileDescriptors(files...)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[target] = ds
+	if c.dir != "" {
+		// the in-memory schema is still usable when the disk is not
+		if err := c.save(target, files); err != nil {
+			log.Println(err)
+		}
+	}
+	return ds, nil
+}
+
+func (c *Cache) set(target string, ds grpcurl.DescriptorSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[target] = ds
+}
+
+// Load reads the last schema of target written to disk and keeps it in memory.
+func (c *Cache) Load(target string) (grpcurl.DescriptorSource, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir == "" {
+		return nil, fmt.Errorf("No schema cached on disk for %q", target)
+	}
+	ds, err := grpcurl.DescriptorSourceFromProtoSets(c.path(target))
+	if err != nil {
+		return nil, err
+	}
+	c.entries[target] = ds
+	return ds, nil
+}
+
+// Invalidate drops the in-memory schema of target, the on-disk copy is kept
+// until it is overwritten.
+func (c *Cache) Invalidate(target string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, target)
+}
+
+func (c *Cache) path(target string) string {
+	return filepath.Join(c.dir, url.PathEscape(target)+".protoset")
+}
+
+func (c *Cache) save(target string, files []*desc.FileDescriptor) error {
+	b, err := marshalFiles(files)
+	if err != nil {
+		return fmt.Errorf("Failed to encode schema of %q due to: %s", target, err.Error())
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("Failed to create cache directory due to: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(c.path(target), b, 0644); err != nil {
+		return fmt.Errorf("Failed to write schema of %q due to: %s", target, err.Error())
+	}
+	return nil
+}
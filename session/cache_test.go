@@ -0,0 +1,113 @@
+package session
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+func hasSymbol(ds grpcurl.DescriptorSource, name string) bool {
+	_, err := ds.FindSymbol(name)
+	return err == nil
+}
+
+// testFiles returns the test schema with its imports, as reflection does.
+func testFiles(t *testing.T) []*desc.FileDescriptor {
+	t.Helper()
+	ds, err := grpcurl.DescriptorSourceFromFileDescriptors(testFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := grpcurl.GetAllFiles(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCache("")
+	if _, err := c.Put("host:1", testFiles(t)); err != nil {
+		t.Fatal(err)
+	}
+	if ds, ok := c.Get("host:1"); !ok || !hasSymbol(ds, "test.Tester") {
+		t.Fatal("Get() after Put() did not return the schema")
+	}
+	if _, err := c.Load("host:1"); err == nil {
+		t.Error("Load() without a directory succeeded")
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Put() without a directory wrote %d files", len(entries))
+	}
+
+	c.SetDir(dir)
+	if _, err := c.Put("host:1", testFiles(t)); err != nil {
+		t.Fatal(err)
+	}
+	c.Invalidate("host:1")
+	if _, ok := c.Get("host:1"); ok {
+		t.Error("Get() after Invalidate() returned a schema")
+	}
+	if _, err := c.Load("host:2"); err == nil {
+		t.Error("Load() of a target never cached succeeded")
+	}
+
+	// a new cache on the same directory, as after a restart
+	reopened := NewCache(dir)
+	ds, err := reopened.Load("host:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasSymbol(ds, "test.Tester.Upload") || !hasSymbol(ds, "google.protobuf.Any") {
+		t.Error("Load() did not return the written schema with its imports")
+	}
+	if _, ok := reopened.Get("host:1"); !ok {
+		t.Error("Get() after Load() did not return the schema")
+	}
+}
+
+func TestSchemaFallsBackToDisk(t *testing.T) {
+	dir := t.TempDir()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	reflection.Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	ctx := context.Background()
+	cfg := Config{Address: lis.Addr().String(), PlainText: true, DialTimeout: time.Second}
+	m := NewManager(NewCache(dir))
+	if _, err := m.Get(cfg).ListServices(ctx); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	srv.Stop()
+
+	offline := NewManager(NewCache(""))
+	defer offline.Close()
+	if _, err := offline.Get(cfg).ListServices(ctx); err == nil {
+		t.Fatal("ListServices() of a stopped server without disk cache succeeded")
+	}
+
+	// a restart with the cache directory still finds the schema
+	restarted := NewManager(NewCache(dir))
+	defer restarted.Close()
+	svcs, err := restarted.Get(cfg).ListServices(ctx)
+	if err != nil {
+		t.Fatalf("ListServices() of a stopped server error = %v", err)
+	}
+	if len(svcs) == 0 {
+		t.Error("ListServices() from the disk cache returned no service")
+	}
+}
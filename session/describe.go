@@ -0,0 +1,352 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+var ErrNoServices = errors.New("Server returned an empty list of exposed services")
+
+// Symbol is a descriptor resolved from the server together with its proto
+// source form, as printed by grpcurl's describe.
+type Symbol struct {
+	Name        string // fully qualified name
+	ElementType string // e.g. "a message", "a service"
+	Descriptor  desc.Descriptor
+	Text        string
+}
+
+func (s Symbol) String() string {
+	return fmt.Sprintf("%s is %s:\n", s.Name, s.ElementType) + fmt.Sprintln(s.Text)
+}
+
+func parseReq(symbols []string, ds grpcurl.DescriptorSource) ([]Symbol, error) {
+	res := make([]Symbol, 0, len(symbols))
+	for _, s := range symbols {
+		s = strings.TrimPrefix(s, ".")
+		if s == "" {
+			return res, errors.New("Failed to resolve symbol due to empty name")
+		}
+
+		dsc, err := ds.FindSymbol(s)
+		if err != nil {
+			return res, fmt.Errorf("Failed to resolve symbol %q due to %s", s, err.Error())
+		}
+		sym, err := describeSymbol(dsc, ds)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, sym)
+	}
+	return res, nil
+}
+
+// DescribeDescriptor prints a descriptor already at hand, e.g. one of the
+// SchemaFiles, in proto source form.
+func DescribeDescriptor(dsc desc.Descriptor) (Symbol, error) {
+	return describeSymbol(dsc, nil)
+}
+
+// describeSymbol prints dsc in proto source form.
+func describeSymbol(dsc desc.Descriptor, ds grpcurl.DescriptorSource) (Symbol, error) {
+	fqn := dsc.GetFullyQualifiedName()
+	var elementType string
+	switch d := dsc.(type) {
+	case *desc.MessageDescriptor:
+		elementType = "a message"
+		parent, ok := d.GetParent().(*desc.MessageDescriptor)
+		if ok {
+			if d.IsMapEntry() {
+				for _, f := range parent.GetFields() {
+					if f.IsMap() && f.GetMessageType() == d {
+						// found it: describe the map field instead
+						elementType = "the entry type for a map field"
+						dsc = f
+						break
+					}
+				}
+			} else {
+				// see if it's a group
+				for _, f := range parent.GetFields() {
+					if f.GetType() == descpb.FieldDescriptorProto_TYPE_GROUP && f.GetMessageType() == d {
+						// found it: describe the map field instead
+						elementType = "the type of a group field"
+						dsc = f
+						break
+					}
+				}
+			}
+		}
+	case *desc.FieldDescriptor:
+		elementType = "a field"
+		if d.GetType() == descpb.FieldDescriptorProto_TYPE_GROUP {
+			elementType = "a group field"
+		} else if d.IsExtension() {
+			elementType = "an extension"
+		}
+	case *desc.OneOfDescriptor:
+		elementType = "a one-of"
+	case *desc.EnumDescriptor:
+		elementType = "an enum"
+	case *desc.EnumValueDescriptor:
+		elementType = "an enum value"
+	case *desc.ServiceDescriptor:
+		elementType = "a service"
+	case *desc.MethodDescriptor:
+		elementType = "a method"
+	case *desc.FileDescriptor:
+		elementType = "a file"
+	default:
+		err := fmt.Errorf("descriptor has unrecognized type %T", dsc)
+		return Symbol{}, fmt.Errorf("Failed to describe symbol %q due to %s", fqn, err.Error())
+	}
+
+	txt, err := grpcurl.GetDescriptorText(dsc, ds)
+	if err != nil {
+		return Symbol{}, fmt.Errorf("Failed to describe symbol %q due to %s", fqn, err.Error())
+	}
+
+	return Symbol{Name: fqn, ElementType: elementType, Descriptor: dsc, Text: txt}, nil
+}
+
+// Describe resolves every service exposed by the server.
+func (s *Session) Describe(ctx context.Context) ([]Symbol, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	svcs, err := grpcurl.ListServices(ds)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list services due to:\n %s", err.Error())
+	}
+	if len(svcs) == 0 {
+		return nil, ErrNoServices
+	}
+	return parseReq(svcs, ds)
+}
+
+// Service is a service exposed by the server.
+type Service struct {
+	Name       string // fully qualified name
+	Descriptor *desc.ServiceDescriptor
+}
+
+// Method is a single rpc of a service.
+type Method struct {
+	Name            string // fully qualified name, i.e. service.method
+	Service         string
+	InputType       string
+	OutputType      string
+	ClientStreaming bool
+	ServerStreaming bool
+	Descriptor      *desc.MethodDescriptor
+}
+
+func newMethod(md *desc.MethodDescriptor) Method {
+	return Method{
+		Name:            md.GetFullyQualifiedName(),
+		Service:         md.GetService().GetFullyQualifiedName(),
+		InputType:       md.GetInputType().GetFullyQualifiedName(),
+		OutputType:      md.GetOutputType().GetFullyQualifiedName(),
+		ClientStreaming: md.IsClientStreaming(),
+		ServerStreaming: md.IsServerStreaming(),
+		Descriptor:      md,
+	}
+}
+
+func findService(ds grpcurl.DescriptorSource, name string) (*desc.ServiceDescriptor, error) {
+	dsc, err := ds.FindSymbol(name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve symbol %q due to %s", name, err.Error())
+	}
+	sd, ok := dsc.(*desc.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("Symbol %q is not a service", name)
+	}
+	return sd, nil
+}
+
+func (s *Session) ListServices(ctx context.Context) ([]Service, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	svcs, err := grpcurl.ListServices(ds)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list services due to:\n %s", err.Error())
+	}
+	res := make([]Service, 0, len(svcs))
+	for _, svc := range svcs {
+		sd, err := findService(ds, svc)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, Service{Name: svc, Descriptor: sd})
+	}
+	return res, nil
+}
+
+func (s *Session) ListMethods(ctx context.Context, serviceName string) ([]Method, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sd, err := findService(ds, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list methods due to:\n %s", err.Error())
+	}
+	res := make([]Method, 0, len(sd.GetMethods()))
+	for _, md := range sd.GetMethods() {
+		res = append(res, newMethod(md))
+	}
+	return res, nil
+}
+
+// TypeNode is a message or enum used by a method, with the types its fields
+// refer to in turn.
+type TypeNode struct {
+	Descriptor desc.Descriptor       // *desc.MessageDescriptor or *desc.EnumDescriptor
+	Field      *desc.FieldDescriptor // referring to the type, nil for the input and output
+	Cycle      bool                  // the type is one of its own ancestors, its fields are left out
+	Children   []*TypeNode
+}
+
+// TypeTree walks the input and output types of md down to the last nested
+// message and enum, wherever they are declared.
+func TypeTree(md *desc.MethodDescriptor) []*TypeNode {
+	return []*TypeNode{
+		typeNode(md.GetInputType(), nil, map[string]bool{}),
+		typeNode(md.GetOutputType(), nil, map[string]bool{}),
+	}
+}
+
+func typeNode(d desc.Descriptor, fd *desc.FieldDescriptor, path map[string]bool) *TypeNode {
+	n := &TypeNode{Descriptor: d, Field: fd}
+	md, ok := d.(*desc.MessageDescriptor)
+	if !ok {
+		return n
+	}
+	name := md.GetFullyQualifiedName()
+	if path[name] {
+		n.Cycle = true
+		return n
+	}
+	path[name] = true
+	defer delete(path, name)
+	for _, f := range md.GetFields() {
+		if t := fieldTypeDescriptor(f); t != nil {
+			n.Children = append(n.Children, typeNode(t, f, path))
+		}
+	}
+	return n
+}
+
+// fieldTypeDescriptor is the message or enum of fd, that of the values for a
+// map, nil for scalars.
+func fieldTypeDescriptor(fd *desc.FieldDescriptor) desc.Descriptor {
+	if fd.IsMap() {
+		fd = fd.GetMapValueType()
+	}
+	if mt := fd.GetMessageType(); mt != nil {
+		return mt
+	}
+	if et := fd.GetEnumType(); et != nil {
+		return et
+	}
+	return nil
+}
+
+// Walk calls fn for n and every type below it, depth first.
+func (n *TypeNode) Walk(fn func(n *TypeNode)) {
+	fn(n)
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// String renders the tree one type per line, children indented below the
+// field referring to them.
+func (n *TypeNode) String() string {
+	var b strings.Builder
+	n.write(&b, "")
+	return b.String()
+}
+
+func (n *TypeNode) write(b *strings.Builder, indent string) {
+	name := n.Descriptor.GetFullyQualifiedName()
+	if _, ok := n.Descriptor.(*desc.EnumDescriptor); ok {
+		name = "enum " + name
+	}
+	if fd := n.Field; fd != nil {
+		switch {
+		case fd.IsMap():
+			key := strings.ToLower(strings.TrimPrefix(fd.GetMapKeyType().GetType().String(), "TYPE_"))
+			name = fmt.Sprintf("map<%s, %s>", key, name)
+		case fd.IsRepeated():
+			name = "repeated " + name
+		}
+		name = fd.GetName() + ": " + name
+	}
+	if n.Cycle {
+		name += " (recursive)"
+	}
+	b.WriteString(indent + name + "\n")
+	for _, c := range n.Children {
+		c.write(b, indent+"  ")
+	}
+}
+
+// Types is the TypeTree of a method, nil for other symbols.
+func (s Symbol) Types() []*TypeNode {
+	if md, ok := s.Descriptor.(*desc.MethodDescriptor); ok {
+		return TypeTree(md)
+	}
+	return nil
+}
+
+// MethodDetails describes the method and every message and enum it depends
+// on, see TypeTree.
+func (s *Session) MethodDetails(ctx context.Context, methodName string) ([]Symbol, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Service/Method is accepted too, as by Invoke
+	m, err := resolveMethod(ds, strings.TrimPrefix(methodName, "."))
+	if err != nil {
+		return nil, err
+	}
+	md := m.Descriptor
+	sym, err := describeSymbol(md, ds)
+	if err != nil {
+		return nil, err
+	}
+	res := []Symbol{sym}
+	seen := map[string]bool{}
+	for _, root := range TypeTree(md) {
+		var walkErr error
+		root.Walk(func(n *TypeNode) {
+			name := n.Descriptor.GetFullyQualifiedName()
+			if seen[name] || walkErr != nil {
+				return
+			}
+			seen[name] = true
+			sym, err := describeSymbol(n.Descriptor, ds)
+			if err != nil {
+				walkErr = err
+				return
+			}
+			res = append(res, sym)
+		})
+		if walkErr != nil {
+			return res, walkErr
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,242 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+)
+
+const testProto = `syntax = "proto3";
+package test;
+
+import "google/protobuf/any.proto";
+import "google/protobuf/duration.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+enum Color {
+  RED = 0;
+  BLUE = 1;
+}
+
+message Node {
+  string name = 1;
+  repeated Node children = 2;
+}
+
+message Request {
+  int32 id = 1;
+  int64 big = 2;
+  bool flag = 3;
+  string name = 4;
+  Color color = 5;
+  repeated string tags = 6;
+  map<string, int32> counts = 7;
+  oneof choice {
+    string text = 8;
+    Node node = 9;
+  }
+  google.protobuf.Timestamp at = 10;
+  google.protobuf.Duration every = 11;
+  google.protobuf.Int64Value limit = 12;
+  google.protobuf.Any extra = 13;
+}
+
+message Reply {
+  repeated Node nodes = 1;
+}
+
+service Tester {
+  rpc Do(Request) returns (Reply);
+  rpc Upload(stream Request) returns (Reply);
+}
+`
+
+func testFile(t *testing.T) *desc.FileDescriptor {
+	t.Helper()
+	p := protoparse.Parser{Accessor: protoparse.FileContentsFromMap(map[string]string{"test.proto": testProto})}
+	fds, err := p.ParseFiles("test.proto")
+	if err != nil {
+		t.Fatalf("parse test.proto: %v", err)
+	}
+	return fds[0]
+}
+
+func testMessage(t *testing.T, name string) *desc.MessageDescriptor {
+	t.Helper()
+	md := testFile(t).FindMessage(name)
+	if md == nil {
+		t.Fatalf("message %s not found", name)
+	}
+	return md
+}
+
+func TestParseReq(t *testing.T) {
+	ds, err := grpcurl.DescriptorSourceFromFileDescriptors(testFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		symbol      string
+		wantName    string
+		elementType string
+		wantErr     string
+	}{
+		{name: "service", symbol: "test.Tester", wantName: "test.Tester", elementType: "a service"},
+		{name: "method", symbol: "test.Tester.Do", wantName: "test.Tester.Do", elementType: "a method"},
+		{name: "message", symbol: "test.Request", wantName: "test.Request", elementType: "a message"},
+		{name: "leading dot", symbol: ".test.Node", wantName: "test.Node", elementType: "a message"},
+		{name: "enum", symbol: "test.Color", wantName: "test.Color", elementType: "an enum"},
+		{name: "empty", symbol: "", wantErr: "empty name"},
+		{name: "dot only", symbol: ".", wantErr: "empty name"},
+		{name: "unknown", symbol: "test.Missing", wantErr: `"test.Missing"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := parseReq([]string{tt.symbol}, ds)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseReq(%q) error = %v, want it to contain %q", tt.symbol, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseReq(%q) error = %v", tt.symbol, err)
+			}
+			if len(res) != 1 {
+				t.Fatalf("parseReq(%q) returned %d symbols, want 1", tt.symbol, len(res))
+			}
+			if res[0].Name != tt.wantName || res[0].ElementType != tt.elementType {
+				t.Errorf("parseReq(%q) = %s (%s), want %s (%s)", tt.symbol, res[0].Name, res[0].ElementType, tt.wantName, tt.elementType)
+			}
+			if res[0].Text == "" {
+				t.Errorf("parseReq(%q) has no proto source", tt.symbol)
+			}
+		})
+	}
+}
+
+const cycleProto = `syntax = "proto3";
+package cycle;
+
+message A {
+  B b = 1;
+}
+
+message B {
+  A a = 1;
+  Leaf left = 2;
+  Leaf right = 3;
+  map<string, A> by_name = 4;
+}
+
+message Leaf {
+  string name = 1;
+}
+
+service Cycles {
+  rpc Get(A) returns (Leaf);
+}
+`
+
+func TestTypeTree(t *testing.T) {
+	p := protoparse.Parser{Accessor: protoparse.FileContentsFromMap(map[string]string{"cycle.proto": cycleProto})}
+	fds, err := p.ParseFiles("cycle.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method *desc.MethodDescriptor
+		want   []string
+	}{
+		{
+			name:   "self recursion",
+			method: testFile(t).FindService("test.Tester").FindMethodByName("Do"),
+			want: []string{
+				`test.Request
+  color: enum test.Color
+  node: test.Node
+    children: repeated test.Node (recursive)
+  at: google.protobuf.Timestamp
+  every: google.protobuf.Duration
+  limit: google.protobuf.Int64Value
+  extra: google.protobuf.Any
+`,
+				`test.Reply
+  nodes: repeated test.Node
+    children: repeated test.Node (recursive)
+`,
+			},
+		},
+		{
+			// a type repeated beside itself is no cycle, only below itself
+			name:   "mutual recursion",
+			method: fds[0].FindService("cycle.Cycles").FindMethodByName("Get"),
+			want: []string{
+				`cycle.A
+  b: cycle.B
+    a: cycle.A (recursive)
+    left: cycle.Leaf
+    right: cycle.Leaf
+    by_name: map<string, cycle.A> (recursive)
+`,
+				"cycle.Leaf\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := TypeTree(tt.method)
+			if len(tree) != len(tt.want) {
+				t.Fatalf("TypeTree() has %d roots, want %d", len(tree), len(tt.want))
+			}
+			for i, root := range tree {
+				if got := root.String(); got != tt.want[i] {
+					t.Errorf("TypeTree()[%d] =\n%s\nwant\n%s", i, got, tt.want[i])
+				}
+				root.Walk(func(n *TypeNode) {
+					if n.Cycle && len(n.Children) != 0 {
+						t.Errorf("recursive %s has children", n.Descriptor.GetFullyQualifiedName())
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestMethodDetails(t *testing.T) {
+	ds, err := grpcurl.DescriptorSourceFromFileDescriptors(testFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Address: "127.0.0.1:1", PlainText: true})
+	s.cache.set(s.target(), ds)
+
+	want := []string{
+		"test.Tester.Do", "test.Request", "test.Color", "test.Node", "google.protobuf.Timestamp",
+		"google.protobuf.Duration", "google.protobuf.Int64Value", "google.protobuf.Any", "test.Reply",
+	}
+	for _, name := range []string{"test.Tester.Do", "test.Tester/Do", ".test.Tester.Do"} {
+		syms, err := s.MethodDetails(context.Background(), name)
+		if err != nil {
+			t.Fatalf("MethodDetails(%q) error = %v", name, err)
+		}
+		got := make([]string, len(syms))
+		for i, sym := range syms {
+			got[i] = sym.Name
+		}
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("MethodDetails(%q) = %v, want every type once: %v", name, got, want)
+		}
+	}
+	if _, err := s.MethodDetails(context.Background(), "test.Request"); err == nil {
+		t.Error("MethodDetails() of a message succeeded")
+	}
+}
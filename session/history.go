@@ -0,0 +1,181 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+// Run is a finished load test as kept in the History.
+type Run struct {
+	ID      string          `json:"id"`
+	Method  string          `json:"method"`
+	Target  string          `json:"target"`
+	Options LoadTestOptions `json:"options"`
+	Report  *runner.Report  `json:"report"` // without the Details of every call
+}
+
+// History keeps past load test runs, one json file per run in its directory.
+type History struct {
+	mu   sync.Mutex
+	dir  string
+	runs []Run // newest first
+}
+
+// OpenHistory reads every run saved in dir, which is created if missing.
+func OpenHistory(dir string) (*History, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Failed to open history due to: %s", err.Error())
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	h := &History{dir: dir}
+	for _, name := range names {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read run %q due to: %s", name, err.Error())
+		}
+		var run Run
+		if err := json.Unmarshal(b, &run); err != nil {
+			return nil, fmt.Errorf("Failed to parse run %q due to: %s", name, err.Error())
+		}
+		if run.Report == nil {
+			return nil, fmt.Errorf("Run %q has no report", name)
+		}
+		run.Report.Details = nil
+		h.runs = append(h.runs, run)
+	}
+	sort.Slice(h.runs, func(i, j int) bool { return h.runs[i].ID > h.runs[j].ID })
+	return h, nil
+}
+
+// Add saves run, giving it an ID from the date of its report. The report is
+// kept without its Details, one per call, which would make the history grow
+// with every request sent.
+func (h *History) Add(run Run) (Run, error) {
+	if run.Report == nil {
+		return run, errors.New("Run has no report")
+	}
+	report := *run.Report
+	report.Details = nil
+	run.Report = &report
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	date := run.Report.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	run.ID = date.UTC().Format("20060102T150405.000000000Z")
+	b, err := json.Marshal(run)
+	if err != nil {
+		return run, err
+	}
+	if err := ioutil.WriteFile(h.path(run.ID), b, 0644); err != nil {
+		return run, fmt.Errorf("Failed to save run due to: %s", err.Error())
+	}
+	h.runs = append([]Run{run}, h.runs...)
+	return run, nil
+}
+
+// Runs returns every run, newest first.
+func (h *History) Runs() []Run {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Run(nil), h.runs...)
+}
+
+func (h *History) Remove(id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, run := range h.runs {
+		if run.ID == id {
+			h.runs = append(h.runs[:i], h.runs[i+1:]...)
+			break
+		}
+	}
+	if err := os.Remove(h.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (h *History) path(id string) string {
+	return filepath.Join(h.dir, id+".json")
+}
+
+// Delta is one metric of two runs side by side.
+type Delta struct {
+	Metric string
+	A, B   float64
+	Unit   string // "ms", "rps", "%" or empty for counts
+
+	// whether a lower value is the better one, e.g. for latencies
+	LowerIsBetter bool
+}
+
+// Change is the relative change from A to B, 0 when A is 0.
+func (d Delta) Change() float64 {
+	if d.A == 0 {
+		return 0
+	}
+	return (d.B - d.A) / d.A
+}
+
+// Regression tells whether B is worse than A.
+func (d Delta) Regression() bool {
+	if d.LowerIsBetter {
+		return d.B > d.A
+	}
+	return d.B < d.A
+}
+
+// Compare lines up the throughput, latency percentiles and error rate of two
+// runs, a being the baseline.
+func Compare(a, b Run) []Delta {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	res := []Delta{
+		{Metric: "count", A: float64(a.Report.Count), B: float64(b.Report.Count)},
+		{Metric: "requests/sec", A: a.Report.Rps, B: b.Report.Rps, Unit: "rps"},
+		{Metric: "average", A: ms(a.Report.Average), B: ms(b.Report.Average), Unit: "ms", LowerIsBetter: true},
+		{Metric: "fastest", A: ms(a.Report.Fastest), B: ms(b.Report.Fastest), Unit: "ms", LowerIsBetter: true},
+		{Metric: "slowest", A: ms(a.Report.Slowest), B: ms(b.Report.Slowest), Unit: "ms", LowerIsBetter: true},
+	}
+	for _, la := range a.Report.LatencyDistribution {
+		for _, lb := range b.Report.LatencyDistribution {
+			if la.Percentage == lb.Percentage {
+				res = append(res, Delta{
+					Metric:        fmt.Sprintf("p%d", la.Percentage),
+					A:             ms(la.Latency),
+					B:             ms(lb.Latency),
+					Unit:          "ms",
+					LowerIsBetter: true,
+				})
+			}
+		}
+	}
+	res = append(res, Delta{Metric: "error rate", A: ErrorRate(a.Report), B: ErrorRate(b.Report), Unit: "%", LowerIsBetter: true})
+	return res
+}
+
+// ErrorRate is the percentage of requests of report that failed.
+func ErrorRate(report *runner.Report) float64 {
+	if report.Count == 0 {
+		return 0
+	}
+	n := 0
+	for _, c := range report.ErrorDist {
+		n += c
+	}
+	return float64(n) / float64(report.Count) * 100
+}
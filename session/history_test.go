@@ -0,0 +1,112 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	h, err := OpenHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		report := &runner.Report{
+			Date:    date.Add(time.Duration(i) * time.Minute),
+			Count:   uint64(i),
+			Details: []runner.ResultDetail{{Latency: time.Millisecond}},
+		}
+		run, err := h.Add(Run{Method: "test.Tester.Do", Report: report})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Details) != 1 {
+			t.Fatal("Add() dropped the details of the caller's report")
+		}
+		ids = append(ids, run.ID)
+	}
+	if err := h.Remove(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hist := range []*History{h, reopened} {
+		runs := hist.Runs()
+		if len(runs) != 2 || runs[0].ID != ids[2] || runs[1].ID != ids[0] {
+			t.Fatalf("Runs() = %v, want %s and %s, newest first", runs, ids[2], ids[0])
+		}
+		if runs[0].Report.Count != 2 || runs[0].Method != "test.Tester.Do" {
+			t.Errorf("Runs()[0] = %+v, want the third run", runs[0])
+		}
+		if len(runs[0].Report.Details) != 0 {
+			t.Errorf("Runs()[0] kept %d call details", len(runs[0].Report.Details))
+		}
+	}
+	if _, err := h.Add(Run{Method: "test.Tester.Do"}); err == nil {
+		t.Error("Add() of a run without report succeeded")
+	}
+	if err := h.Remove("missing"); err != nil {
+		t.Errorf("Remove() of a missing run error = %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	a := Run{Report: &runner.Report{
+		Count:   100,
+		Rps:     50,
+		Average: 20 * time.Millisecond,
+		Fastest: 5 * time.Millisecond,
+		Slowest: 80 * time.Millisecond,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 18 * time.Millisecond},
+			{Percentage: 99, Latency: 70 * time.Millisecond},
+		},
+		ErrorDist: map[string]int{"unavailable": 2},
+	}}
+	b := Run{Report: &runner.Report{
+		Count:   100,
+		Rps:     40,
+		Average: 10 * time.Millisecond,
+		Fastest: 5 * time.Millisecond,
+		Slowest: 90 * time.Millisecond,
+		LatencyDistribution: []runner.LatencyDistribution{
+			{Percentage: 50, Latency: 9 * time.Millisecond},
+			{Percentage: 90, Latency: 30 * time.Millisecond},
+		},
+	}}
+	tests := []struct {
+		metric     string
+		a, b       float64
+		change     float64
+		regression bool
+	}{
+		{metric: "count", a: 100, b: 100},
+		{metric: "requests/sec", a: 50, b: 40, change: -0.2, regression: true},
+		{metric: "average", a: 20, b: 10, change: -0.5},
+		{metric: "fastest", a: 5, b: 5},
+		{metric: "slowest", a: 80, b: 90, change: 0.125, regression: true},
+		{metric: "p50", a: 18, b: 9, change: -0.5},
+		{metric: "error rate", a: 2, b: 0, change: -1},
+	}
+	got := Compare(a, b)
+	if len(got) != len(tests) {
+		t.Fatalf("Compare() returned %v, want the metrics of both runs only", got)
+	}
+	for i, tt := range tests {
+		d := got[i]
+		if d.Metric != tt.metric || d.A != tt.a || d.B != tt.b {
+			t.Errorf("delta %d = %s %v -> %v, want %s %v -> %v", i, d.Metric, d.A, d.B, tt.metric, tt.a, tt.b)
+		}
+		if d.Change() != tt.change || d.Regression() != tt.regression {
+			t.Errorf("%s change = %v regression = %v, want %v and %v", d.Metric, d.Change(), d.Regression(), tt.change, tt.regression)
+		}
+	}
+}
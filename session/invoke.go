@@ -0,0 +1,42 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+)
+
+// InvokeResult is the outcome of a unary call that reached the server.
+type InvokeResult struct {
+	Output string // responses formatted as json
+	CallInfo
+}
+
+// Invoke calls methodName ("service.method" or "service/method") with the json
+// message msg, the call is bounded by ctx.
+func (s *Session) Invoke(ctx context.Context, methodName, msg string) (*InvokeResult, error) {
+	descSource, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cc, release, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	rf, formatter, err := grpcurl.RequestParserAndFormatterFor(grpcurl.Format("json"), descSource, true, true, strings.NewReader(msg))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to construct request parser and formatter for json due to: %s", err.Error())
+	}
+	var out bytes.Buffer
+	h := NewRecorder(descSource, grpcurl.NewDefaultEventHandler(&out, descSource, formatter, false))
+	err = grpcurl.InvokeRPC(ctx, descSource, cc, methodName, s.cfg.Metadata.headers(), h, rf.Next)
+	if err != nil {
+		return nil, fmt.Errorf("Error invoking method %s due to: %s", methodName, err.Error())
+	}
+	return &InvokeResult{Output: out.String(), CallInfo: h.Info}, nil
+}
@@ -0,0 +1,270 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bojand/ghz/printer"
+	"github.com/bojand/ghz/runner"
+)
+
+// LoadTestOptions are the knobs of a ghz run exposed by the GUI, zero values
+// fall back to the ghz defaults unless noted.
+type LoadTestOptions struct {
+	Name          string            `json:"name"`
+	Tags          map[string]string `json:"tags"`
+	Concurrency   uint              `json:"concurrency"`
+	Connections   uint              `json:"connections"`
+	TotalRequests uint              `json:"total_requests"`
+	Duration      time.Duration     `json:"duration"` // stops the run early, 0 is unbounded
+	RPS           uint              `json:"rps"`      // 0 is unlimited
+	Timeout       time.Duration     `json:"timeout"`  // of every request
+	Keepalive     time.Duration     `json:"keepalive"`
+
+	// streaming calls only
+	StreamInterval        time.Duration `json:"stream_interval"`
+	StreamCallDuration    time.Duration `json:"stream_call_duration"`
+	StreamCallCount       uint          `json:"stream_call_count"`
+	StreamDynamicMessages bool          `json:"stream_dynamic_messages"`
+
+	Profile LoadProfile `json:"profile"`
+}
+
+// DefaultLoadTestOptions are the options of a new run.
+func DefaultLoadTestOptions() LoadTestOptions {
+	return LoadTestOptions{
+		Concurrency:   20,
+		Connections:   1,
+		TotalRequests: 500,
+		Duration:      5 * time.Second,
+		Timeout:       20 * time.Second,
+	}
+}
+
+// LoadTest runs ghz against methodName with the messages of payload.
+// Cancelling ctx stops the run early, the partial report is still returned.
+// mon, when not nil, follows the run as it goes.
+func (s *Session) LoadTest(ctx context.Context, methodName string, payload Payload, opts LoadTestOptions, mon *Monitor) (*runner.Report, error) {
+	// cpu
+	nCPU := runtime.GOMAXPROCS(-1)
+
+	options, err := s.credentials()
+	if err != nil {
+		return nil, err
+	}
+	options = append(options,
+		runner.WithDialTimeout(s.cfg.DialTimeout),
+		runner.WithCPUs(uint(nCPU)),
+	)
+	data, err := payload.options()
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, data...)
+	if err := opts.Profile.Validate(); err != nil {
+		return nil, err
+	}
+	options = append(options, opts.options()...)
+	options = append(options, opts.Profile.options()...)
+
+	if err := s.cfg.Metadata.Validate(); err != nil {
+		return nil, err
+	}
+	md, err := s.cfg.Metadata.Map()
+	if err != nil {
+		return nil, err
+	}
+	if len(md) != 0 {
+		options = append(options, runner.WithReflectionMetadata(md))
+	}
+	if mon != nil {
+		mon.reset(opts.TotalRequests)
+		options = append(options, mon.options(md)...)
+	} else if len(md) != 0 {
+		options = append(options, runner.WithMetadata(md))
+	}
+
+	if s.cfg.Schema.Kind != SchemaReflection {
+		b, err := s.protoset(ctx)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, runner.WithProtosetBinary(b))
+	}
+
+	return run(ctx, methodName, s.cfg.Address, opts.Duration, options)
+}
+
+// options maps opts onto ghz, leaving out what is unset.
+func (opts LoadTestOptions) options() []runner.Option {
+	options := []runner.Option{
+		runner.WithConcurrency(opts.Concurrency),
+		runner.WithTotalRequests(opts.TotalRequests),
+		runner.WithRPS(opts.RPS),
+		runner.WithStreamDynamicMessages(opts.StreamDynamicMessages),
+	}
+	// ghz ignores the total once it has a duration, which is a maximum here
+	// and enforced by run
+	if opts.TotalRequests == 0 {
+		options = append(options, runner.WithRunDuration(opts.Duration))
+	}
+	if opts.Name != "" {
+		options = append(options, runner.WithName(opts.Name))
+	}
+	if len(opts.Tags) != 0 {
+		options = append(options, runner.WithTags(opts.Tags))
+	}
+	if opts.Connections != 0 {
+		options = append(options, runner.WithConnections(opts.Connections))
+	}
+	if opts.Timeout != 0 {
+		options = append(options, runner.WithTimeout(opts.Timeout))
+	}
+	if opts.Keepalive != 0 {
+		options = append(options, runner.WithKeepalive(opts.Keepalive))
+	}
+	if opts.StreamInterval != 0 {
+		options = append(options, runner.WithStreamInterval(opts.StreamInterval))
+	}
+	if opts.StreamCallDuration != 0 {
+		options = append(options, runner.WithStreamCallDuration(opts.StreamCallDuration))
+	}
+	if opts.StreamCallCount != 0 {
+		options = append(options, runner.WithStreamCallCount(opts.StreamCallCount))
+	}
+	return options
+}
+
+// credentials are the ghz options matching the connection settings used by
+// invoke.
+func (s *Session) credentials() ([]runner.Option, error) {
+	var options []runner.Option
+	if s.cfg.Authority != "" {
+		options = append(options, runner.WithAuthority(s.cfg.Authority))
+	}
+	if s.cfg.PlainText {
+		return append(options, runner.WithInsecure(true)), nil
+	}
+
+	ca := s.cfg.CA
+	if err := ca.Validate(); err != nil {
+		return nil, err
+	}
+	options = append(options, runner.WithInsecure(false), runner.WithSkipTLSVerify(ca.Insecure))
+	if ca.CACert != "" {
+		options = append(options, runner.WithRootCertificate(ca.CACert))
+	}
+	if ca.Cert != "" {
+		options = append(options, runner.WithCertificate(ca.Cert, ca.Key))
+	}
+	if s.cfg.ServerName != "" {
+		options = append(options, runner.WithServerNameOverride(s.cfg.ServerName))
+	}
+	return options, nil
+}
+
+// run is runner.Run with a context instead of SIGINT handling.
+func run(ctx context.Context, call, host string, duration time.Duration, options []runner.Option) (*runner.Report, error) {
+	c, err := runner.NewConfig(call, host, options...)
+	if err != nil {
+		return nil, err
+	}
+	reqr, err := runner.NewRequester(c)
+	if err != nil {
+		return nil, err
+	}
+
+	finished := make(chan struct{})
+	defer close(finished)
+	stop := func(reason runner.StopReason) {
+		// the requester panics when stopped after its run is over
+		defer func() { _ = recover() }()
+		reqr.Stop(reason)
+	}
+	go func() {
+		var timeout <-chan time.Time
+		if duration > 0 {
+			t := time.NewTimer(duration)
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case <-ctx.Done():
+			stop(runner.ReasonCancel)
+		case <-timeout:
+			stop(runner.ReasonTimeout)
+		case <-finished:
+		}
+	}()
+
+	return reqr.Run()
+}
+
+// RPSPoint is the throughput of one interval of a run.
+type RPSPoint struct {
+	Start time.Duration // since the first result
+	RPS   float64
+}
+
+// RPSOverTime buckets the results of report by interval. The last bucket is
+// usually partial, so its rate reads low.
+func RPSOverTime(report *runner.Report, interval time.Duration) []RPSPoint {
+	if len(report.Details) == 0 || interval <= 0 {
+		return nil
+	}
+	first, last := report.Details[0].Timestamp, report.Details[0].Timestamp
+	for _, d := range report.Details {
+		if d.Timestamp.Before(first) {
+			first = d.Timestamp
+		}
+		if d.Timestamp.After(last) {
+			last = d.Timestamp
+		}
+	}
+	counts := make([]int, int(last.Sub(first)/interval)+1)
+	for _, d := range report.Details {
+		counts[int(d.Timestamp.Sub(first)/interval)]++
+	}
+	res := make([]RPSPoint, len(counts))
+	for i, n := range counts {
+		res[i] = RPSPoint{Start: time.Duration(i) * interval, RPS: float64(n) / interval.Seconds()}
+	}
+	return res
+}
+
+// ReportFormats are the ghz printer formats a report can be written in.
+var ReportFormats = []string{"summary", "json", "pretty", "csv", "html", "influx-summary", "influx-details", "prometheus"}
+
+// SaveReport writes report to the file at path in one of ReportFormats.
+func SaveReport(path string, report *runner.Report, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Failed to save report due to: %s", err.Error())
+	}
+	if err := WriteReport(f, report, format); err != nil {
+		f.Close()
+		return fmt.Errorf("Failed to write %s report due to: %s", format, err.Error())
+	}
+	return f.Close()
+}
+
+// WriteReport writes report to w in one of the ghz printer formats, e.g. "summary".
+func WriteReport(w io.Writer, report *runner.Report, format string) error {
+	p := printer.ReportPrinter{
+		Report: report,
+		Out:    w,
+	}
+	return p.Print(format)
+}
+
+// FormatReport renders report in one of the ghz printer formats.
+func FormatReport(report *runner.Report, format string) (string, error) {
+	var buf strings.Builder
+	err := WriteReport(&buf, report, format)
+	return buf.String(), err
+}
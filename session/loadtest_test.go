@@ -0,0 +1,168 @@
+package session
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+func TestRPSOverTime(t *testing.T) {
+	begin := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(offsets ...time.Duration) *runner.Report {
+		r := &runner.Report{}
+		for _, o := range offsets {
+			r.Details = append(r.Details, runner.ResultDetail{Timestamp: begin.Add(o)})
+		}
+		return r
+	}
+	tests := []struct {
+		name     string
+		report   *runner.Report
+		interval time.Duration
+		want     []RPSPoint
+	}{
+		{name: "no details", report: at(), interval: time.Second},
+		{name: "no interval", report: at(0), interval: 0},
+		{
+			name:     "single result",
+			report:   at(0),
+			interval: time.Second,
+			want:     []RPSPoint{{Start: 0, RPS: 1}},
+		},
+		{
+			name:     "unordered with a gap",
+			report:   at(2500*time.Millisecond, 0, 100*time.Millisecond, 2*time.Second),
+			interval: time.Second,
+			want:     []RPSPoint{{Start: 0, RPS: 2}, {Start: time.Second, RPS: 0}, {Start: 2 * time.Second, RPS: 2}},
+		},
+		{
+			name:     "sub second interval",
+			report:   at(0, 100*time.Millisecond, 600*time.Millisecond),
+			interval: 500 * time.Millisecond,
+			want:     []RPSPoint{{Start: 0, RPS: 4}, {Start: 500 * time.Millisecond, RPS: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RPSOverTime(tt.report, tt.interval); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RPSOverTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTestTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	addr := tlsServer(t, pki)
+	client := CA{CACert: pki.CACert, Cert: pki.ClientCert, Key: pki.ClientKey}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		ok   bool
+		want runner.Options
+	}{
+		{
+			name: "verified with client certificate",
+			cfg:  Config{CA: client, ServerName: "example.test"},
+			ok:   true,
+			want: runner.Options{CACert: pki.CACert, Cert: pki.ClientCert, Key: pki.ClientKey, CName: "example.test"},
+		},
+		{
+			name: "skip verify with authority",
+			cfg:  Config{CA: CA{Insecure: true, Cert: pki.ClientCert, Key: pki.ClientKey}, Authority: "example.test"},
+			ok:   true,
+			want: runner.Options{Cert: pki.ClientCert, Key: pki.ClientKey, SkipTLS: true, Authority: "example.test"},
+		},
+		{name: "wrong server name", cfg: Config{CA: client, ServerName: "other.test"}},
+		{name: "plaintext", cfg: Config{PlainText: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Address = addr
+			cfg.DialTimeout = time.Second
+			opts := LoadTestOptions{Concurrency: 1, Connections: 1, TotalRequests: 2, Timeout: time.Second}
+			report, err := New(cfg).LoadTest(context.Background(), "grpc.health.v1.Health/Check", Payload{Text: "{}"}, opts, nil)
+			ok := err == nil && report.StatusCodeDist["OK"] == 2
+			if ok != tt.ok {
+				t.Fatalf("LoadTest() succeeded = %v, want %v (error %v)", ok, tt.ok, err)
+			}
+			if !ok {
+				return
+			}
+			got := report.Options
+			if got.Insecure || got.CACert != tt.want.CACert || got.Cert != tt.want.Cert || got.Key != tt.want.Key ||
+				got.CName != tt.want.CName || got.SkipTLS != tt.want.SkipTLS || got.Authority != tt.want.Authority {
+				t.Errorf("LoadTest() ran with %+v, want the security settings of %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadTestOptions(t *testing.T) {
+	addr := testServer(t, func(srv *grpc.Server) {
+		healthpb.RegisterHealthServer(srv, health.NewServer())
+		reflection.Register(srv)
+	})
+
+	tests := []struct {
+		name      string
+		opts      LoadTestOptions
+		want      runner.Options
+		wantCount uint64
+	}{
+		{
+			name: "every option",
+			opts: LoadTestOptions{
+				Name:          "run",
+				Tags:          map[string]string{"env": "test"},
+				Concurrency:   2,
+				Connections:   2,
+				TotalRequests: 6,
+				Duration:      time.Minute,
+				RPS:           1000,
+				Timeout:       3 * time.Second,
+				Keepalive:     30 * time.Second,
+			},
+			want:      runner.Options{Name: "run", Concurrency: 2, Connections: 2, Total: 6, RPS: 1000, Timeout: 3 * time.Second, KeepaliveTime: 30 * time.Second},
+			wantCount: 6,
+		},
+		{
+			name: "duration only",
+			opts: LoadTestOptions{Concurrency: 1, Duration: 200 * time.Millisecond},
+			// ghz runs an unbounded total for a duration
+			want: runner.Options{Concurrency: 1, Connections: 1, Total: math.MaxInt32, Duration: 200 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(Config{Address: addr, PlainText: true, DialTimeout: time.Second})
+			report, err := s.LoadTest(context.Background(), "grpc.health.v1.Health/Check", Payload{Text: "{}"}, tt.opts, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := report.Options
+			if got.Name != tt.want.Name || got.Concurrency != tt.want.Concurrency || got.Connections != tt.want.Connections ||
+				got.Total != tt.want.Total || got.RPS != tt.want.RPS || got.Duration != tt.want.Duration ||
+				(tt.want.Timeout != 0 && got.Timeout != tt.want.Timeout) || got.KeepaliveTime != tt.want.KeepaliveTime {
+				t.Errorf("LoadTest() ran with %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(report.Tags, tt.opts.Tags) {
+				t.Errorf("LoadTest() tags = %v, want %v", report.Tags, tt.opts.Tags)
+			}
+			// the call in flight when a duration is over may fail
+			if ok := report.StatusCodeDist["OK"]; ok == 0 || (tt.wantCount != 0 && uint64(ok) != tt.wantCount) {
+				t.Errorf("LoadTest() status codes = %v of %d calls", report.StatusCodeDist, report.Count)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"sync"
+)
+
+// Manager keeps a single connection alive and hands out sessions on it for
+// as long as the address, TLS settings and authority stay the same, whatever
+// their metadata or schema. Asking for a different connection closes the
+// previous one once its calls and streams are done.
+//
+// The schema cache is shared by all sessions handed out.
+type Manager struct {
+	mu      sync.Mutex
+	current *Session
+	cache   *Cache
+}
+
+func NewManager(cache *Cache) *Manager {
+	return &Manager{cache: cache}
+}
+
+// Get returns a session for cfg, on the current connection when it matches.
+func (m *Manager) Get(cfg Config) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := New(cfg)
+	s.cache = m.cache
+	if m.current != nil {
+		if m.current.cfg.linkKey() == s.cfg.linkKey() {
+			s.link = m.current.link
+		} else {
+			_ = m.current.link.retire()
+		}
+	}
+	m.current = s
+	return s
+}
+
+// Current returns the session handed out last, or nil.
+func (m *Manager) Current() *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
+
+// Close closes the current connection once it is unused, if any.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		return nil
+	}
+	err := m.current.Close()
+	m.current = nil
+	return err
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Header is a single request metadata entry. Values of binary headers, whose
+// key ends in "-bin", are given base64 encoded.
+type Header struct {
+	Key   string
+	Value string
+}
+
+// Metadata is sent with reflection requests, calls and load tests.
+type Metadata []Header
+
+var base64Codecs = []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding}
+
+func decodeBinary(val string) (string, error) {
+	var firstErr error
+	for _, d := range base64Codecs {
+		b, err := d.DecodeString(val)
+		if err == nil {
+			return string(b), nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", firstErr
+}
+
+func isBinary(key string) bool {
+	return strings.HasSuffix(strings.ToLower(key), "-bin")
+}
+
+// Validate checks keys are legal header names and binary values decode.
+func (md Metadata) Validate() error {
+	for _, h := range md {
+		key := strings.ToLower(strings.TrimSpace(h.Key))
+		if key == "" {
+			return fmt.Errorf("Metadata with value %q has no key", h.Value)
+		}
+		for _, r := range key {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+				return fmt.Errorf("Metadata key %q contains illegal character %q", h.Key, r)
+			}
+		}
+		if strings.HasPrefix(key, "grpc-") {
+			return fmt.Errorf("Metadata key %q is reserved", h.Key)
+		}
+		if isBinary(key) {
+			if _, err := decodeBinary(strings.TrimSpace(h.Value)); err != nil {
+				return fmt.Errorf("Value of binary metadata %q is not base64 encoded: %s", h.Key, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// headers is the "key: value" form taken by grpcurl, which decodes binary
+// values itself.
+func (md Metadata) headers() []string {
+	res := make([]string, 0, len(md))
+	for _, h := range md {
+		res = append(res, h.Key+": "+h.Value)
+	}
+	return res
+}
+
+func (md Metadata) outgoing() metadata.MD {
+	return metadata.MD(md.raw())
+}
+
+// raw returns the decoded values of every key.
+func (md Metadata) raw() map[string][]string {
+	res := map[string][]string{}
+	for _, h := range md {
+		key := strings.ToLower(strings.TrimSpace(h.Key))
+		val := strings.TrimSpace(h.Value)
+		if isBinary(key) {
+			if v, err := decodeBinary(val); err == nil {
+				val = v
+			}
+		}
+		res[key] = append(res[key], val)
+	}
+	return res
+}
+
+// Map is the form taken by ghz, which sends a single value per key. The values
+// of a repeated key are comma-joined as in a http header, which binary values
+// cannot be.
+func (md Metadata) Map() (map[string]string, error) {
+	res := map[string]string{}
+	for k, v := range md.raw() {
+		if len(v) > 1 && isBinary(k) {
+			return nil, fmt.Errorf("Binary metadata %q is given %d times, load tests take a single value", k, len(v))
+		}
+		res[k] = strings.Join(v, ",")
+	}
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      Metadata
+		wantErr bool
+	}{
+		{name: "empty", md: nil},
+		{name: "plain", md: Metadata{{Key: "Authorization", Value: "Bearer x"}, {Key: "x-request.id_1", Value: "1"}}},
+		{name: "binary", md: Metadata{{Key: "trace-bin", Value: "aGVsbG8="}}},
+		{name: "binary unpadded", md: Metadata{{Key: "trace-bin", Value: "aGVsbG8"}}},
+		{name: "no key", md: Metadata{{Key: " ", Value: "x"}}, wantErr: true},
+		{name: "illegal character", md: Metadata{{Key: "x y", Value: "x"}}, wantErr: true},
+		{name: "reserved", md: Metadata{{Key: "grpc-timeout", Value: "1S"}}, wantErr: true},
+		{name: "binary not base64", md: Metadata{{Key: "trace-bin", Value: "not base64!"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.md.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMetadataMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      Metadata
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", md: nil, want: map[string]string{}},
+		{
+			name: "keys are lower cased and trimmed",
+			md:   Metadata{{Key: " Authorization ", Value: " Bearer x "}},
+			want: map[string]string{"authorization": "Bearer x"},
+		},
+		{
+			name: "binary values are decoded",
+			md:   Metadata{{Key: "Trace-Bin", Value: "aGVsbG8="}},
+			want: map[string]string{"trace-bin": "hello"},
+		},
+		{
+			name: "repeated keys are joined",
+			md:   Metadata{{Key: "x-tag", Value: "a"}, {Key: "X-Tag", Value: "b"}, {Key: "other", Value: "c"}},
+			want: map[string]string{"x-tag": "a,b", "other": "c"},
+		},
+		{
+			name:    "repeated binary keys",
+			md:      Metadata{{Key: "trace-bin", Value: "aGVsbG8="}, {Key: "trace-bin", Value: "aGk="}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.md.Map()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Map() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Map() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/bojand/ghz/runner"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// PayloadKind is where the request messages of a load test come from.
+type PayloadKind int
+
+const (
+	PayloadJSON      PayloadKind = iota // a json message or an array of them
+	PayloadJSONLines                    // a file with one json message per line
+	PayloadBinary                       // a file of protobuf messages, count-delimited or a single one
+)
+
+func (k PayloadKind) String() string {
+	switch k {
+	case PayloadJSONLines:
+		return "json lines file"
+	case PayloadBinary:
+		return "binary protobuf file"
+	default:
+		return "json"
+	}
+}
+
+// Payload is the data of a load test. JSON messages may use the ghz template
+// variables, e.g. {{.RequestNumber}}, {{.TimestampUnix}}, {{.UUID}},
+// {{randomString 8}} or {{randomInt 1 100}}. Arrays are sent round robin, one
+// message per call, or all of them for client streaming calls.
+type Payload struct {
+	Kind PayloadKind
+	Text string // PayloadJSON only
+	File string // the other kinds
+}
+
+var errNoPayload = errors.New("No request payload given")
+
+// json returns the payload as a single json document, lines becoming an array.
+func (p Payload) json() (string, error) {
+	switch p.Kind {
+	case PayloadJSON:
+		if strings.TrimSpace(p.Text) == "" {
+			return "", errNoPayload
+		}
+		return strings.TrimSpace(p.Text), nil
+	case PayloadJSONLines:
+		if p.File == "" {
+			return "", errNoPayload
+		}
+		b, err := ioutil.ReadFile(p.File)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read payload due to: %s", err.Error())
+		}
+		var lines []string
+		sc := bufio.NewScanner(bytes.NewReader(b))
+		sc.Buffer(nil, 16<<20)
+		for sc.Scan() {
+			if line := strings.TrimSpace(sc.Text()); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return "", fmt.Errorf("Failed to read payload due to: %s", err.Error())
+		}
+		if len(lines) == 0 {
+			return "", errNoPayload
+		}
+		return "[" + strings.Join(lines, ",") + "]", nil
+	}
+	return "", fmt.Errorf("Payload of kind %s is not json", p.Kind)
+}
+
+func (p Payload) options() ([]runner.Option, error) {
+	if p.Kind == PayloadBinary {
+		if p.File == "" {
+			return nil, errNoPayload
+		}
+		return []runner.Option{runner.WithBinaryDataFromFile(p.File)}, nil
+	}
+	data, err := p.json()
+	if err != nil {
+		return nil, err
+	}
+	return []runner.Option{runner.WithDataFromJSON(data)}, nil
+}
+
+// PreviewPayloads renders the messages of the first n calls of a load test
+// against methodName, one json document per call. Random values are samples,
+// ghz draws its own during the run.
+func (s *Session) PreviewPayloads(ctx context.Context, methodName string, p Payload, n int) ([]string, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m, err := resolveMethod(ds, methodName)
+	if err != nil {
+		return nil, err
+	}
+	marshaler := jsonpb.Marshaler{Indent: "  "}
+
+	if p.Kind == PayloadBinary {
+		msgs, err := readBinary(p.File, m.Descriptor.GetInputType())
+		if err != nil {
+			return nil, err
+		}
+		var res []string
+		for i := 0; i < n && i < len(msgs); i++ {
+			txt, err := marshaler.MarshalToString(msgs[i])
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, txt)
+		}
+		return res, nil
+	}
+
+	data, err := p.json()
+	if err != nil {
+		return nil, err
+	}
+	var elems []string
+	if strings.HasPrefix(data, "[") {
+		var arr []json.RawMessage
+		if err := json.Unmarshal([]byte(data), &arr); err != nil {
+			return nil, fmt.Errorf("Failed to parse payload due to: %s", err.Error())
+		}
+		if len(arr) == 0 {
+			return nil, errNoPayload
+		}
+		for _, e := range arr {
+			elems = append(elems, string(e))
+		}
+	} else {
+		elems = []string{data}
+	}
+
+	funcs := previewFuncs(rand.New(rand.NewSource(time.Now().UnixNano())))
+	var res []string
+	for i := 0; i < n; i++ {
+		cd := newCallData(m.Descriptor, int64(i))
+		// client streaming calls send every message of the array at once
+		call := elems
+		if !m.ClientStreaming {
+			call = elems[i%len(elems):][:1]
+		}
+		var msgs []string
+		for _, e := range call {
+			txt, err := renderTemplate(e, cd, funcs)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to render payload of call %d due to: %s", i+1, err.Error())
+			}
+			dm := dynamic.NewMessage(m.Descriptor.GetInputType())
+			if err := jsonpb.UnmarshalString(txt, dm); err != nil {
+				return nil, fmt.Errorf("Failed to parse payload of call %d due to: %s", i+1, err.Error())
+			}
+			if txt, err = marshaler.MarshalToString(dm); err != nil {
+				return nil, err
+			}
+			msgs = append(msgs, txt)
+		}
+		res = append(res, strings.Join(msgs, "\n"))
+	}
+	return res, nil
+}
+
+// readBinary decodes count-delimited messages, falling back to a single
+// message like ghz does.
+func readBinary(file string, md *desc.MessageDescriptor) ([]proto.Message, error) {
+	if file == "" {
+		return nil, errNoPayload
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read payload due to: %s", err.Error())
+	}
+	var msgs []proto.Message
+	buf := proto.NewBuffer(b)
+	for {
+		dm := dynamic.NewMessage(md)
+		if err := buf.DecodeMessage(dm); err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			msgs = nil
+			break
+		}
+		msgs = append(msgs, dm)
+	}
+	if len(msgs) != 0 {
+		return msgs, nil
+	}
+	dm := dynamic.NewMessage(md)
+	if err := proto.Unmarshal(b, dm); err != nil {
+		return nil, fmt.Errorf("Failed to decode payload as %s due to: %s", md.GetFullyQualifiedName(), err.Error())
+	}
+	return []proto.Message{dm}, nil
+}
+
+// newCallData fills the template variables ghz sets for a call.
+func newCallData(md *desc.MethodDescriptor, reqNum int64) *runner.CallData {
+	now := time.Now()
+	return &runner.CallData{
+		WorkerID:           "preview",
+		RequestNumber:      reqNum,
+		FullyQualifiedName: md.GetFullyQualifiedName(),
+		MethodName:         md.GetName(),
+		ServiceName:        md.GetService().GetName(),
+		InputName:          md.GetInputType().GetName(),
+		OutputName:         md.GetOutputType().GetName(),
+		IsClientStreaming:  md.IsClientStreaming(),
+		IsServerStreaming:  md.IsServerStreaming(),
+		Timestamp:          now.Format(time.RFC3339),
+		TimestampUnix:      now.Unix(),
+		TimestampUnixMilli: now.UnixNano() / int64(time.Millisecond),
+		TimestampUnixNano:  now.UnixNano(),
+		UUID:               uuid.New().String(),
+	}
+}
+
+// previewFuncs are the functions ghz adds to sprig's for payload templates.
+// ghz does not export its random ones, these draw sample values from rng
+// within the same bounds.
+func previewFuncs(rng *rand.Rand) template.FuncMap {
+	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	return template.FuncMap{
+		"newUUID": func() string { return uuid.New().String() },
+		"randomString": func(n int) string {
+			if n <= 0 {
+				n = rng.Intn(15) + 2
+			}
+			b := make([]byte, n)
+			for i := range b {
+				b[i] = charset[rng.Intn(len(charset))]
+			}
+			return string(b)
+		},
+		"randomInt": func(min, max int) int {
+			if min < 0 {
+				min = 0
+			}
+			if max <= 0 {
+				max = 1
+			}
+			return rng.Intn(max-min) + min
+		},
+	}
+}
+
+func renderTemplate(text string, cd *runner.CallData, funcs template.FuncMap) (string, error) {
+	t, err := template.New("payload").Funcs(sprig.TxtFuncMap()).Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := t.Execute(&buf, cd); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
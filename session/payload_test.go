@@ -0,0 +1,157 @@
+package session
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bojand/ghz/runner"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+func TestReadBinary(t *testing.T) {
+	md := testMessage(t, "test.Node")
+	node := func(name string) *dynamic.Message {
+		m := dynamic.NewMessage(md)
+		m.SetFieldByName("name", name)
+		return m
+	}
+	single, err := proto.Marshal(node("single"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := proto.NewBuffer(nil)
+	for _, name := range []string{"first", "second"} {
+		if err := buf.EncodeMessage(node(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    []string
+		wantErr bool
+	}{
+		{name: "single message", content: single, want: []string{"single"}},
+		{name: "length delimited", content: buf.Bytes(), want: []string{"first", "second"}},
+		{name: "empty file", content: nil, want: []string{""}},
+		{name: "truncated", content: single[:len(single)-1], wantErr: true},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, string(rune('a'+i))+".bin")
+			if err := ioutil.WriteFile(file, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			msgs, err := readBinary(file, md)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("readBinary() = %v, want an error", msgs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readBinary() error = %v", err)
+			}
+			if len(msgs) != len(tt.want) {
+				t.Fatalf("readBinary() returned %d messages, want %d", len(msgs), len(tt.want))
+			}
+			for j, m := range msgs {
+				if got := m.(*dynamic.Message).GetFieldByName("name"); got != tt.want[j] {
+					t.Errorf("message %d name = %q, want %q", j, got, tt.want[j])
+				}
+			}
+		})
+	}
+
+	t.Run("no file", func(t *testing.T) {
+		if _, err := readBinary("", md); err != errNoPayload {
+			t.Errorf("readBinary(\"\") error = %v, want %v", err, errNoPayload)
+		}
+	})
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := readBinary(filepath.Join(dir, "missing.bin"), md); err == nil {
+			t.Error("readBinary() of a missing file succeeded")
+		}
+	})
+}
+
+func TestRenderTemplate(t *testing.T) {
+	cd := &runner.CallData{RequestNumber: 3, MethodName: "Do"}
+	tests := []struct {
+		text    string
+		want    string
+		wantErr string
+	}{
+		{text: `{"name": "plain"}`, want: `{"name": "plain"}`},
+		{text: `{"id": {{.RequestNumber}}, "name": "{{.MethodName}}"}`, want: `{"id": 3, "name": "Do"}`},
+		{text: `{"name": "{{upper "x"}}"}`, want: `{"name": "X"}`},
+		{text: `{"name": "{{.Missing}}"}`, wantErr: "Missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := renderTemplate(tt.text, cd, previewFuncs(rand.New(rand.NewSource(1))))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("renderTemplate() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderTemplate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderTemplate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewRandomFuncs(t *testing.T) {
+	cd := &runner.CallData{}
+	funcs := previewFuncs(rand.New(rand.NewSource(1)))
+	tests := []struct {
+		text  string
+		check func(string) bool
+	}{
+		{text: `{{randomInt 5 10}}`, check: intIn(5, 9)},
+		{text: `{{randomInt -3 2}}`, check: intIn(0, 1)},
+		{text: `{{randomInt 0 0}}`, check: intIn(0, 0)},
+		{text: `{{randomString 8}}`, check: stringLen(8, 8)},
+		{text: `{{randomString 0}}`, check: stringLen(2, 16)},
+		{text: `{{newUUID}}`, check: stringLen(36, 36)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				got, err := renderTemplate(tt.text, cd, funcs)
+				if err != nil {
+					t.Fatalf("renderTemplate() error = %v", err)
+				}
+				if !tt.check(got) {
+					t.Fatalf("renderTemplate() = %q, out of bounds", got)
+				}
+			}
+		})
+	}
+}
+
+func intIn(min, max int) func(string) bool {
+	return func(s string) bool {
+		n, err := strconv.Atoi(s)
+		return err == nil && n >= min && n <= max
+	}
+}
+
+func stringLen(min, max int) func(string) bool {
+	return func(s string) bool {
+		return len(s) >= min && len(s) <= max
+	}
+}
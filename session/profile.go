@@ -0,0 +1,258 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bojand/ghz/load"
+	"github.com/bojand/ghz/runner"
+)
+
+// ProfileKind is the shape of the load of a run over time.
+type ProfileKind int
+
+const (
+	ProfileFixed            ProfileKind = iota // the concurrency and rate limit of the options
+	ProfileRateRamp                            // rps grows linearly from Start to End, Step per second
+	ProfileRateSteps                           // rps goes from Start to End by Step every StepDuration
+	ProfileConcurrencySteps                    // workers go from Start to End by Step every StepDuration
+	ProfileConstantRate                        // Start calls per second, whether earlier ones returned or not
+)
+
+func (k ProfileKind) String() string {
+	switch k {
+	case ProfileRateRamp:
+		return "rps ramp"
+	case ProfileRateSteps:
+		return "rps steps"
+	case ProfileConcurrencySteps:
+		return "concurrency steps"
+	case ProfileConstantRate:
+		return "constant arrival rate"
+	default:
+		return "fixed"
+	}
+}
+
+// LoadProfile schedules the load of a run, results are reported per stage:
+// every StepDuration for steps and ramps.
+type LoadProfile struct {
+	Kind         ProfileKind   `json:"kind"`
+	Start        uint          `json:"start"`
+	End          uint          `json:"end"`
+	Step         int           `json:"step"`
+	StepDuration time.Duration `json:"step_duration"`
+}
+
+// Validate checks the profile describes a schedule ghz can run.
+func (p LoadProfile) Validate() error {
+	switch p.Kind {
+	case ProfileFixed:
+		return nil
+	case ProfileConstantRate:
+		if p.Start == 0 {
+			return errors.New("Constant arrival rate needs a rate to start at")
+		}
+		return nil
+	}
+	if p.Start == p.End {
+		return fmt.Errorf("Profile %s needs different start and end values", p.Kind)
+	}
+	if p.Step == 0 || (p.End > p.Start) != (p.Step > 0) {
+		return fmt.Errorf("Profile %s needs a step going from start to end", p.Kind)
+	}
+	if p.StepDuration <= 0 {
+		return fmt.Errorf("Profile %s needs a step duration", p.Kind)
+	}
+	return nil
+}
+
+func (p LoadProfile) options() []runner.Option {
+	switch p.Kind {
+	case ProfileRateRamp:
+		return []runner.Option{
+			runner.WithLoadSchedule(runner.ScheduleLine),
+			runner.WithLoadStart(p.Start),
+			runner.WithLoadEnd(p.End),
+			runner.WithLoadStep(p.Step),
+		}
+	case ProfileRateSteps:
+		return []runner.Option{
+			runner.WithLoadSchedule(runner.ScheduleStep),
+			runner.WithLoadStart(p.Start),
+			runner.WithLoadEnd(p.End),
+			runner.WithLoadStep(p.Step),
+			runner.WithLoadStepDuration(p.StepDuration),
+		}
+	case ProfileConcurrencySteps:
+		return []runner.Option{
+			runner.WithConcurrencySchedule(runner.ScheduleStep),
+			runner.WithConcurrencyStart(p.Start),
+			runner.WithConcurrencyEnd(p.End),
+			runner.WithConcurrencyStep(p.Step),
+			runner.WithConcurrencyStepDuration(p.StepDuration),
+			runner.WithWorkerTicker(newStepTicker(p)),
+		}
+	case ProfileConstantRate:
+		return []runner.Option{runner.WithRPS(p.Start), runner.WithAsync(true)}
+	}
+	return nil
+}
+
+// stepTicker adds or removes workers like load.StepWorkerTicker, which sends
+// on its closed channel and panics when the run ends before the last step.
+type stepTicker struct {
+	p      LoadProfile
+	c      chan load.TickValue
+	done   chan struct{}
+	exited chan struct{}
+	once   sync.Once
+}
+
+func newStepTicker(p LoadProfile) *stepTicker {
+	return &stepTicker{
+		p:      p,
+		c:      make(chan load.TickValue),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+}
+
+func (t *stepTicker) Ticker() <-chan load.TickValue {
+	return t.c
+}
+
+func (t *stepTicker) Run() {
+	defer close(t.exited)
+	if !t.send(load.TickValue{Delta: int(t.p.Start)}) {
+		return
+	}
+	tick := time.NewTicker(t.p.StepDuration)
+	defer tick.Stop()
+	wc := int(t.p.Start)
+	for {
+		select {
+		case <-tick.C:
+		case <-t.done:
+			return
+		}
+		next := wc + t.p.Step
+		last := (t.p.Step > 0 && next >= int(t.p.End)) || (t.p.Step < 0 && next <= int(t.p.End))
+		if last {
+			next = int(t.p.End)
+		}
+		if !t.send(load.TickValue{Delta: next - wc, Done: last}) || last {
+			return
+		}
+		wc = next
+	}
+}
+
+func (t *stepTicker) send(tv load.TickValue) bool {
+	select {
+	case t.c <- tv:
+		return true
+	case <-t.done:
+		return false
+	}
+}
+
+// Finish stops Run before closing the channel.
+func (t *stepTicker) Finish() {
+	t.once.Do(func() {
+		close(t.done)
+		<-t.exited
+		close(t.c)
+	})
+}
+
+// level is the scheduled rps or concurrency at elapsed time t.
+func (p LoadProfile) level(t time.Duration) float64 {
+	var v float64
+	if p.Kind == ProfileRateRamp {
+		v = float64(p.Start) + float64(p.Step)*t.Seconds()
+	} else {
+		v = float64(p.Start) + float64(p.Step)*float64(t/p.StepDuration)
+	}
+	if (p.Step > 0 && v > float64(p.End)) || (p.Step < 0 && v < float64(p.End)) {
+		v = float64(p.End)
+	}
+	return v
+}
+
+// StageResult sums up the calls started during one stage of a profile.
+type StageResult struct {
+	Start   time.Duration // since the start of the run
+	Level   float64       // scheduled rps or concurrency, 0 for fixed profiles
+	Count   int
+	Errors  int
+	RPS     float64
+	Average time.Duration
+	Latency []runner.LatencyDistribution // p50, p90 and p99
+}
+
+// Stages splits the calls of report by the stages of p, a fixed or constant
+// rate profile being a single stage.
+func (p LoadProfile) Stages(report *runner.Report) []StageResult {
+	if len(report.Details) == 0 {
+		return nil
+	}
+	begin := report.Date.Add(-report.Total)
+	stage := func(time.Duration) int { return 0 }
+	if p.Kind != ProfileFixed && p.Kind != ProfileConstantRate && p.StepDuration > 0 {
+		stage = func(t time.Duration) int { return int(t / p.StepDuration) }
+	}
+
+	byStage := map[int][]runner.ResultDetail{}
+	for _, d := range report.Details {
+		t := d.Timestamp.Add(-d.Latency).Sub(begin)
+		if t < 0 {
+			t = 0
+		}
+		byStage[stage(t)] = append(byStage[stage(t)], d)
+	}
+	idx := make([]int, 0, len(byStage))
+	for i := range byStage {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+
+	res := make([]StageResult, 0, len(idx))
+	for _, i := range idx {
+		details := byStage[i]
+		r := StageResult{Count: len(details)}
+		span := report.Total
+		if p.Kind != ProfileFixed && p.Kind != ProfileConstantRate && p.StepDuration > 0 {
+			r.Start = time.Duration(i) * p.StepDuration
+			r.Level = p.level(r.Start)
+			span = p.StepDuration
+			if rest := report.Total - r.Start; rest < span {
+				span = rest
+			}
+		} else if p.Kind == ProfileConstantRate {
+			r.Level = float64(p.Start)
+		}
+		if span > 0 {
+			r.RPS = float64(r.Count) / span.Seconds()
+		}
+		lat := make([]time.Duration, len(details))
+		var sum time.Duration
+		for j, d := range details {
+			if d.Error != "" {
+				r.Errors++
+			}
+			lat[j] = d.Latency
+			sum += d.Latency
+		}
+		r.Average = sum / time.Duration(len(lat))
+		sort.Slice(lat, func(a, b int) bool { return lat[a] < lat[b] })
+		for _, pct := range []int{50, 90, 99} {
+			r.Latency = append(r.Latency, runner.LatencyDistribution{Percentage: pct, Latency: lat[(len(lat)-1)*pct/100]})
+		}
+		res = append(res, r)
+	}
+	return res
+}
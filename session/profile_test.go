@@ -0,0 +1,103 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+)
+
+func TestLoadProfileStages(t *testing.T) {
+	begin := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// detail is a call started at start after the beginning of the run
+	detail := func(start, latency time.Duration, err string) runner.ResultDetail {
+		return runner.ResultDetail{Timestamp: begin.Add(start + latency), Latency: latency, Error: err}
+	}
+	report := &runner.Report{
+		Date:  begin.Add(2500 * time.Millisecond),
+		Total: 2500 * time.Millisecond,
+		Details: []runner.ResultDetail{
+			detail(500*time.Millisecond, 40*time.Millisecond, ""),
+			detail(1200*time.Millisecond, 10*time.Millisecond, ""),
+			detail(1800*time.Millisecond, 30*time.Millisecond, "unavailable"),
+			detail(2100*time.Millisecond, 20*time.Millisecond, ""),
+		},
+	}
+	steps := LoadProfile{Kind: ProfileRateSteps, Start: 10, End: 30, Step: 10, StepDuration: time.Second}
+
+	tests := []struct {
+		name    string
+		profile LoadProfile
+		report  *runner.Report
+		want    []StageResult
+	}{
+		{
+			name:    "fixed",
+			profile: LoadProfile{},
+			report:  report,
+			want:    []StageResult{{Count: 4, Errors: 1, RPS: 1.6, Average: 25 * time.Millisecond}},
+		},
+		{
+			name:    "constant rate",
+			profile: LoadProfile{Kind: ProfileConstantRate, Start: 5},
+			report:  report,
+			want:    []StageResult{{Level: 5, Count: 4, Errors: 1, RPS: 1.6, Average: 25 * time.Millisecond}},
+		},
+		{
+			name:    "steps",
+			profile: steps,
+			report:  report,
+			want: []StageResult{
+				{Start: 0, Level: 10, Count: 1, RPS: 1, Average: 40 * time.Millisecond},
+				{Start: time.Second, Level: 20, Count: 2, Errors: 1, RPS: 2, Average: 20 * time.Millisecond},
+				{Start: 2 * time.Second, Level: 30, Count: 1, RPS: 2, Average: 20 * time.Millisecond},
+			},
+		},
+		{
+			name:    "no details",
+			profile: steps,
+			report:  &runner.Report{Total: time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.profile.Stages(tt.report)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Stages() returned %d stages, want %d", len(got), len(tt.want))
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Start != w.Start || g.Level != w.Level || g.Count != w.Count || g.Errors != w.Errors || g.RPS != w.RPS || g.Average != w.Average {
+					t.Errorf("stage %d = %+v, want %+v", i, g, w)
+				}
+				if len(g.Latency) != 3 {
+					t.Errorf("stage %d has %d percentiles, want p50, p90 and p99", i, len(g.Latency))
+				}
+			}
+		})
+	}
+}
+
+func TestLoadProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile LoadProfile
+		wantErr bool
+	}{
+		{name: "fixed", profile: LoadProfile{}},
+		{name: "constant rate", profile: LoadProfile{Kind: ProfileConstantRate, Start: 10}},
+		{name: "constant rate without rate", profile: LoadProfile{Kind: ProfileConstantRate}, wantErr: true},
+		{name: "ramp up", profile: LoadProfile{Kind: ProfileRateRamp, Start: 1, End: 10, Step: 1, StepDuration: time.Second}},
+		{name: "ramp down", profile: LoadProfile{Kind: ProfileRateRamp, Start: 10, End: 1, Step: -1, StepDuration: time.Second}},
+		{name: "step away from end", profile: LoadProfile{Kind: ProfileRateSteps, Start: 1, End: 10, Step: -1, StepDuration: time.Second}, wantErr: true},
+		{name: "same start and end", profile: LoadProfile{Kind: ProfileConcurrencySteps, Start: 5, End: 5, Step: 1, StepDuration: time.Second}, wantErr: true},
+		{name: "no step duration", profile: LoadProfile{Kind: ProfileConcurrencySteps, Start: 1, End: 5, Step: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.profile.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package session
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bojand/ghz/runner"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// progressWindow is how far back the live rate and latencies look.
+const progressWindow = 5 * time.Second
+
+// maxSamples bounds the latencies kept for the live percentiles, the older
+// half is dropped once it is reached.
+const maxSamples = 10000
+
+// Progress is a snapshot of a running load test.
+type Progress struct {
+	Completed uint64
+	Total     uint64 // 0 when the run is bounded by its duration only
+	Errors    uint64
+	Statuses  map[string]int
+	Elapsed   time.Duration
+
+	// over the last few seconds
+	RPS     float64
+	Latency []runner.LatencyDistribution
+}
+
+// Monitor follows a load test while it runs, its snapshots are safe to take
+// from any goroutine.
+//
+// ghz does not report single calls until the run is over. Each call is timed
+// from its stream being created, when its per call credentials are asked for,
+// to its context being done.
+type Monitor struct {
+	mu        sync.Mutex
+	begin     time.Time
+	total     uint64
+	running   bool
+	completed uint64
+	errors    uint64
+	statuses  map[string]int
+	finishes  []time.Time
+	samples   []sample
+}
+
+type sample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{statuses: map[string]int{}}
+}
+
+// reset starts following a new run.
+func (m *Monitor) reset(total uint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.begin = time.Now()
+	m.total = uint64(total)
+	m.running = false
+	m.completed, m.errors = 0, 0
+	m.statuses = map[string]int{}
+	m.finishes, m.samples = nil, nil
+}
+
+func (m *Monitor) started() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = true
+}
+
+func (m *Monitor) finished(err error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// the reflection call of ghz finishes before any call started
+	if !m.running {
+		return
+	}
+	m.completed++
+	if err != nil {
+		m.errors++
+	}
+	m.statuses[status.Code(err).String()]++
+	m.finishes = append(m.finishes, now)
+}
+
+// timeCall records the latency of the call of ctx once it is over.
+func (m *Monitor) timeCall(ctx context.Context) {
+	m.mu.Lock()
+	running := m.running
+	m.mu.Unlock()
+	if !running {
+		return
+	}
+	start := time.Now()
+	go func() {
+		<-ctx.Done()
+		now := time.Now()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if len(m.samples) == maxSamples {
+			m.samples = append(m.samples[:0], m.samples[maxSamples/2:]...)
+		}
+		m.samples = append(m.samples, sample{at: now, latency: now.Sub(start)})
+	}()
+}
+
+// Snapshot returns the progress so far.
+func (m *Monitor) Snapshot() Progress {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	recent := func(at time.Time) bool { return now.Sub(at) <= progressWindow }
+	i := sort.Search(len(m.finishes), func(i int) bool { return recent(m.finishes[i]) })
+	m.finishes = m.finishes[i:]
+	// samples are appended once their goroutine runs, roughly in order
+	for len(m.samples) != 0 && !recent(m.samples[0].at) {
+		m.samples = m.samples[1:]
+	}
+
+	p := Progress{
+		Completed: m.completed,
+		Total:     m.total,
+		Errors:    m.errors,
+		Statuses:  make(map[string]int, len(m.statuses)),
+	}
+	for k, v := range m.statuses {
+		p.Statuses[k] = v
+	}
+	if m.begin.IsZero() {
+		return p
+	}
+	p.Elapsed = now.Sub(m.begin)
+
+	window := progressWindow
+	if p.Elapsed < window {
+		window = p.Elapsed
+	}
+	if window > 0 {
+		p.RPS = float64(len(m.finishes)) / window.Seconds()
+	}
+	if len(m.samples) != 0 {
+		lat := make([]time.Duration, len(m.samples))
+		for i, s := range m.samples {
+			lat[i] = s.latency
+		}
+		sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+		for _, pct := range []int{50, 90, 95, 99} {
+			p.Latency = append(p.Latency, runner.LatencyDistribution{
+				Percentage: pct,
+				Latency:    lat[(len(lat)-1)*pct/100],
+			})
+		}
+	}
+	return p
+}
+
+// callTimer hands the context of every call to the monitor, per call
+// credentials being the only hook of ghz that sees it.
+type callTimer struct {
+	m *Monitor
+}
+
+func (t callTimer) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	t.m.timeCall(ctx)
+	return nil, nil
+}
+
+func (callTimer) RequireTransportSecurity() bool {
+	return false
+}
+
+// options hooks m into a run, replacing the static metadata of md with a
+// provider that renders the same templates.
+func (m *Monitor) options(md map[string]string) []runner.Option {
+	return []runner.Option{
+		runner.WithDefaultCallOptions([]grpc.CallOption{
+			grpc.OnFinish(m.finished),
+			grpc.PerRPCCredentials(callTimer{m}),
+		}),
+		runner.WithMetadataProvider(func(cd *runner.CallData) (*metadata.MD, error) {
+			res := metadata.MD{}
+			for k, v := range md {
+				b, err := cd.ExecuteData(v)
+				if err != nil {
+					return nil, err
+				}
+				res.Set(k, string(b))
+			}
+			m.started()
+			return &res, nil
+		}),
+	}
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMonitor(t *testing.T) {
+	m := NewMonitor()
+	m.reset(10)
+
+	// ghz's reflection call, before any call started
+	m.finished(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.timeCall(ctx)
+	cancel()
+
+	m.started()
+	for i, latency := range []time.Duration{10 * time.Millisecond, 30 * time.Millisecond} {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.timeCall(ctx)
+		time.Sleep(latency)
+		cancel()
+		if i == 0 {
+			m.finished(nil)
+		} else {
+			m.finished(errors.New("unavailable"))
+		}
+	}
+
+	// the latencies are recorded once their goroutines see the cancel
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		m.mu.Lock()
+		n := len(m.samples)
+		m.mu.Unlock()
+		if n == 2 {
+			break
+		}
+	}
+	p := m.Snapshot()
+	if p.Completed != 2 || p.Errors != 1 || p.Total != 10 {
+		t.Errorf("Snapshot() = %d completed, %d errors of %d, want 2, 1 of 10", p.Completed, p.Errors, p.Total)
+	}
+	if p.Statuses["OK"] != 1 || p.Statuses["Unknown"] != 1 {
+		t.Errorf("Snapshot() statuses = %v, want one OK and one Unknown", p.Statuses)
+	}
+	if len(p.Latency) != 4 {
+		t.Fatalf("Snapshot() has %d percentiles, want p50, p90, p95 and p99", len(p.Latency))
+	}
+	// of two calls every percentile is the faster one
+	for _, l := range p.Latency {
+		if l.Latency < 10*time.Millisecond || l.Latency >= 30*time.Millisecond {
+			t.Errorf("p%d = %s, want the latency of the first call", l.Percentage, l.Latency)
+		}
+	}
+
+	m.reset(5)
+	p = m.Snapshot()
+	if p.Completed != 0 || p.Errors != 0 || len(p.Statuses) != 0 || len(p.Latency) != 0 || p.Total != 5 {
+		t.Errorf("Snapshot() after reset = %+v, want a fresh run of 5", p)
+	}
+	m.finished(nil)
+	if p = m.Snapshot(); p.Completed != 0 {
+		t.Errorf("Snapshot() after reset counts a call finishing before any started")
+	}
+}
@@ -0,0 +1,120 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails" // ErrorInfo, BadRequest, RetryInfo, ...
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// StatusDetail is one decoded entry of the google.rpc.Status details.
+type StatusDetail struct {
+	Type string // fully qualified message name, e.g. google.rpc.ErrorInfo
+	JSON string
+}
+
+// CallInfo is what the server sent back on a call besides the responses.
+type CallInfo struct {
+	Headers  metadata.MD
+	Trailers metadata.MD
+	Status   *status.Status
+	Details  []StatusDetail
+}
+
+// Recorder is a grpcurl.InvocationEventHandler that records response
+// headers, trailers and the final status of a call while passing every event
+// on to Next.
+type Recorder struct {
+	Next grpcurl.InvocationEventHandler
+	Info CallInfo
+
+	ds grpcurl.DescriptorSource
+}
+
+// NewRecorder returns a Recorder decoding status details with the message
+// types of ds, next may be nil.
+func NewRecorder(ds grpcurl.DescriptorSource, next grpcurl.InvocationEventHandler) *Recorder {
+	return &Recorder{Next: next, ds: ds}
+}
+
+func (r *Recorder) OnResolveMethod(md *desc.MethodDescriptor) {
+	if r.Next != nil {
+		r.Next.OnResolveMethod(md)
+	}
+}
+
+func (r *Recorder) OnSendHeaders(md metadata.MD) {
+	if r.Next != nil {
+		r.Next.OnSendHeaders(md)
+	}
+}
+
+func (r *Recorder) OnReceiveHeaders(md metadata.MD) {
+	r.Info.Headers = md
+	if r.Next != nil {
+		r.Next.OnReceiveHeaders(md)
+	}
+}
+
+func (r *Recorder) OnReceiveResponse(resp proto.Message) {
+	if r.Next != nil {
+		r.Next.OnReceiveResponse(resp)
+	}
+}
+
+func (r *Recorder) OnReceiveTrailers(stat *status.Status, md metadata.MD) {
+	r.Info.Status = stat
+	r.Info.Trailers = md
+	r.Info.Details = decodeDetails(r.ds, stat)
+	if r.Next != nil {
+		r.Next.OnReceiveTrailers(stat, md)
+	}
+}
+
+func decodeDetails(ds grpcurl.DescriptorSource, stat *status.Status) []StatusDetail {
+	var res []StatusDetail
+	m := jsonpb.Marshaler{AnyResolver: grpcurl.AnyResolverFromDescriptorSourceWithFallback(ds)}
+	for _, d := range stat.Proto().GetDetails() {
+		name := d.GetTypeUrl()
+		if pos := strings.LastIndex(name, "/"); pos >= 0 {
+			name = name[pos+1:]
+		}
+		txt, err := m.MarshalToString(d)
+		if err != nil {
+			txt = fmt.Sprintf("Failed to decode detail due to: %s", err.Error())
+		} else {
+			// indented here, jsonpb does not indent types of the schema
+			var b bytes.Buffer
+			if json.Indent(&b, []byte(txt), "", "  ") == nil {
+				txt = b.String()
+			}
+		}
+		res = append(res, StatusDetail{Type: name, JSON: txt})
+	}
+	return res
+}
+
+// FormatMetadata renders headers or trailers one "key: value" per line.
+func FormatMetadata(md metadata.MD) string {
+	return grpcurl.MetadataToString(md)
+}
+
+// StatusText renders the code, message and details of the final status.
+func (ci CallInfo) StatusText() string {
+	if ci.Status == nil {
+		return ""
+	}
+	res := fmt.Sprintf("code: %s\nmessage: %s\n", ci.Status.Code(), ci.Status.Message())
+	for _, d := range ci.Details {
+		res += fmt.Sprintf("\n%s\n%s\n", d.Type, d.JSON)
+	}
+	return res
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecorderStatusDetails(t *testing.T) {
+	ds, err := grpcurl.DescriptorSourceFromFileDescriptors(testFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := proto.Marshal(&errdetails.ErrorInfo{Reason: "QUOTA", Domain: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := dynamic.NewMessage(testMessage(t, "test.Node"))
+	node.SetFieldByName("name", "leaf")
+	nodeBytes, err := node.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat := status.FromProto(&spb.Status{
+		Code:    int32(codes.ResourceExhausted),
+		Message: "slow down",
+		Details: []*any.Any{
+			{TypeUrl: "type.googleapis.com/google.rpc.ErrorInfo", Value: info},
+			{TypeUrl: "type.googleapis.com/test.Node", Value: nodeBytes},
+			{TypeUrl: "type.googleapis.com/unknown.Thing", Value: []byte{0x08, 0x01}},
+		},
+	})
+
+	r := NewRecorder(ds, nil)
+	r.OnReceiveHeaders(metadata.Pairs("x-header", "h"))
+	r.OnReceiveTrailers(stat, metadata.Pairs("x-trailer", "t"))
+
+	if got := r.Info.Headers.Get("x-header"); len(got) != 1 || got[0] != "h" {
+		t.Errorf("Headers = %v", r.Info.Headers)
+	}
+	if got := r.Info.Trailers.Get("x-trailer"); len(got) != 1 || got[0] != "t" {
+		t.Errorf("Trailers = %v", r.Info.Trailers)
+	}
+	want := []struct{ typ, contains string }{
+		{"google.rpc.ErrorInfo", `"reason": "QUOTA"`},
+		{"test.Node", `"name": "leaf"`},
+		{"unknown.Thing", "unknown.Thing"},
+	}
+	if len(r.Info.Details) != len(want) {
+		t.Fatalf("Details = %v, want %d entries", r.Info.Details, len(want))
+	}
+	for i, w := range want {
+		d := r.Info.Details[i]
+		if d.Type != w.typ || !strings.Contains(d.JSON, w.contains) {
+			t.Errorf("Details[%d] = %+v, want type %s with %q", i, d, w.typ, w.contains)
+		}
+	}
+
+	text := r.Info.StatusText()
+	for _, s := range []string{"code: ResourceExhausted", "message: slow down", "google.rpc.ErrorInfo", `"domain": "example.com"`} {
+		if !strings.Contains(text, s) {
+			t.Errorf("StatusText() = %q, want it to contain %q", text, s)
+		}
+	}
+	if (CallInfo{}).StatusText() != "" {
+		t.Error("StatusText() without status is not empty")
+	}
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/proto"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// SchemaKind selects where the descriptors of the target come from.
+type SchemaKind int
+
+const (
+	SchemaReflection SchemaKind = iota // google.golang.org/grpc/reflection on the server
+	SchemaProtoFiles                   // .proto source files
+	SchemaProtosets                    // compiled FileDescriptorSet files
+)
+
+func (k SchemaKind) String() string {
+	switch k {
+	case SchemaProtoFiles:
+		return "proto files"
+	case SchemaProtosets:
+		return "protoset files"
+	default:
+		return "reflection"
+	}
+}
+
+// Schema is the descriptor source of a session, for servers that do not
+// expose reflection.
+type Schema struct {
+	Kind        SchemaKind
+	Files       []string // .proto or protoset files, depending on Kind
+	ImportPaths []string // only used for .proto files
+}
+
+var errNoSchemaFiles = errors.New("No proto or protoset files given")
+
+// target is the key of the schema in the cache.
+func (s *Session) target() string {
+	sc := s.cfg.Schema
+	switch sc.Kind {
+	case SchemaProtoFiles:
+		return "proto:" + strings.Join(sc.ImportPaths, ",") + ":" + strings.Join(sc.Files, ",")
+	case SchemaProtosets:
+		return "protoset:" + strings.Join(sc.Files, ",")
+	default:
+		return s.cfg.Address
+	}
+}
+
+func (s *Session) loadFiles() (grpcurl.DescriptorSource, error) {
+	sc := s.cfg.Schema
+	if len(sc.Files) == 0 {
+		return nil, errNoSchemaFiles
+	}
+	var (
+		ds  grpcurl.DescriptorSource
+		err error
+	)
+	if sc.Kind == SchemaProtoFiles {
+		ds, err = grpcurl.DescriptorSourceFromProtoFiles(sc.ImportPaths, sc.Files...)
+	} else {
+		ds, err = grpcurl.DescriptorSourceFromProtoSets(sc.Files...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load %s due to: %s", sc.Kind, err.Error())
+	}
+	s.cache.set(s.target(), ds)
+	return ds, nil
+}
+
+// SchemaFiles returns the files declaring the services of the target and
+// every file they import.
+func (s *Session) SchemaFiles(ctx context.Context) ([]*desc.FileDescriptor, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, err := grpcurl.GetAllFiles(ds)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load the files of the schema due to: %s", err.Error())
+	}
+	return files, nil
+}
+
+func marshalFiles(files []*desc.FileDescriptor) ([]byte, error) {
+	fds := &descpb.FileDescriptorSet{}
+	for _, fd := range files {
+		fds.File = append(fds.File, fd.AsFileDescriptorProto())
+	}
+	return proto.Marshal(fds)
+}
+
+// protoset encodes the schema of a file based session, so that ghz does not
+// need reflection either.
+func (s *Session) protoset(ctx context.Context) ([]byte, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, err := grpcurl.GetAllFiles(ds)
+	if err != nil {
+		return nil, err
+	}
+	return marshalFiles(files)
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "protos"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "protos", "test.proto"), []byte(testProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := marshalFiles(testFiles(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoset := filepath.Join(dir, "test.protoset")
+	if err := ioutil.WriteFile(protoset, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		schema  Schema
+		wantErr string
+	}{
+		{
+			name:   "proto files",
+			schema: Schema{Kind: SchemaProtoFiles, Files: []string{"test.proto"}, ImportPaths: []string{filepath.Join(dir, "protos")}},
+		},
+		{
+			name:   "protosets",
+			schema: Schema{Kind: SchemaProtosets, Files: []string{protoset}},
+		},
+		{
+			name:    "no files",
+			schema:  Schema{Kind: SchemaProtoFiles},
+			wantErr: errNoSchemaFiles.Error(),
+		},
+		{
+			name:    "missing proto file",
+			schema:  Schema{Kind: SchemaProtoFiles, Files: []string{"missing.proto"}, ImportPaths: []string{dir}},
+			wantErr: "Failed to load proto files",
+		},
+		{
+			name:    "proto file given as protoset",
+			schema:  Schema{Kind: SchemaProtosets, Files: []string{filepath.Join(dir, "protos", "test.proto")}},
+			wantErr: "Failed to load protoset files",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// nothing listens there, the files are the only schema
+			s := New(Config{Address: "127.0.0.1:1", PlainText: true, Schema: tt.schema})
+			svcs, err := s.ListServices(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ListServices() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(svcs) != 1 || svcs[0].Name != "test.Tester" {
+				t.Fatalf("ListServices() = %v, want test.Tester", svcs)
+			}
+			methods, err := s.ListMethods(context.Background(), "test.Tester")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(methods) != 2 {
+				t.Errorf("ListMethods() returned %d methods, want 2", len(methods))
+			}
+		})
+	}
+}
+
+func TestRefreshSchemaReloadsFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.proto")
+	if err := ioutil.WriteFile(file, []byte(testProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := New(Config{Address: "127.0.0.1:1", PlainText: true, Schema: Schema{Kind: SchemaProtoFiles, Files: []string{"test.proto"}, ImportPaths: []string{dir}}})
+	ctx := context.Background()
+	if _, err := s.ListServices(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := testProto + "\nservice Other {\n  rpc Do(Request) returns (Reply);\n}\n"
+	if err := ioutil.WriteFile(file, []byte(changed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if svcs, _ := s.ListServices(ctx); len(svcs) != 1 {
+		t.Errorf("ListServices() before refresh returned %d services, want the cached 1", len(svcs))
+	}
+	if err := s.RefreshSchema(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if svcs, _ := s.ListServices(ctx); len(svcs) != 2 {
+		t.Errorf("ListServices() after refresh returned %d services, want 2", len(svcs))
+	}
+}
@@ -0,0 +1,275 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// DefaultDialTimeout is used when Config.DialTimeout is zero.
+const DefaultDialTimeout = 10 * time.Second
+
+// CA holds the TLS material used when the connection is not plain text.
+// All of them are PEM file names, Cert and Key are set together for mTLS.
+type CA struct {
+	Insecure bool   // skip verification of the server certificate
+	CACert   string // root CA bundle, the system pool when empty
+	Cert     string // client certificate
+	Key      string // client private key
+}
+
+// Config describes how to reach the target server.
+type Config struct {
+	Address     string
+	PlainText   bool
+	ServerName  string
+	CA          CA
+	DialTimeout time.Duration
+	Authority   string // :authority pseudo header, the address when empty
+	Schema      Schema
+	Metadata    Metadata
+}
+
+// Session runs the describe/list/invoke/load-test operations of the GUI
+// against a single server, without depending on Qt.
+//
+// The connection is dialed on first use and reused until Close. Sessions handed
+// out by a Manager share it when they differ in metadata or schema only.
+type Session struct {
+	cfg   Config
+	cache *Cache
+	link  *link
+}
+
+func New(cfg Config) *Session {
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = DefaultDialTimeout
+	}
+	return &Session{cfg: cfg, cache: NewCache(""), link: &link{}}
+}
+
+// linkKey is the part of a Config the connection depends on.
+type linkKey struct {
+	Address     string
+	PlainText   bool
+	ServerName  string
+	CA          CA
+	DialTimeout time.Duration
+	Authority   string
+}
+
+func (cfg Config) linkKey() linkKey {
+	return linkKey{cfg.Address, cfg.PlainText, cfg.ServerName, cfg.CA, cfg.DialTimeout, cfg.Authority}
+}
+
+// link is the connection of one or more sessions. It counts the calls and
+// streams using it, so that closing it waits for them to end.
+//
+// Dialing happens outside of mu, which State takes on the GUI thread.
+type link struct {
+	mu      sync.Mutex
+	cc      *grpc.ClientConn
+	dialing chan struct{} // closed once the dial in progress, if any, is over
+	users   int
+	retired bool // close once the last user is done
+}
+
+func (l *link) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.users--
+	if l.users == 0 && l.retired {
+		_ = l.closeLocked()
+	}
+}
+
+// retire closes the connection now when it is unused, or else once the last
+// user is done.
+func (l *link) retire() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.users > 0 || l.dialing != nil {
+		l.retired = true
+		return nil
+	}
+	return l.closeLocked()
+}
+
+func (l *link) closeLocked() error {
+	l.retired = false
+	if l.cc == nil {
+		return nil
+	}
+	err := l.cc.Close()
+	l.cc = nil
+	return err
+}
+
+func (s *Session) Config() Config {
+	return s.cfg
+}
+
+// State reports the connectivity state of the underlying connection, ok is
+// false when the session has not dialed yet.
+func (s *Session) State() (state connectivity.State, ok bool) {
+	s.link.mu.Lock()
+	defer s.link.mu.Unlock()
+	if s.link.cc == nil {
+		return state, false
+	}
+	return s.link.cc.GetState(), true
+}
+
+// Close releases the connection once the calls and streams still using it
+// are done, the session can still be used afterwards and will dial again.
+func (s *Session) Close() error {
+	return s.link.retire()
+}
+
+func generateCreds(plainText bool, serverName string, ca *CA) (creds credentials.TransportCredentials, err error) {
+	if !plainText {
+		if err := ca.Validate(); err != nil {
+			return nil, err
+		}
+		creds, err = grpcurl.ClientTransportCredentials(ca.Insecure, ca.CACert, ca.Cert, ca.Key)
+		if err != nil {
+			return creds, fmt.Errorf("Failed to configure transport credentials due to: %s", err.Error())
+		}
+		if serverName != "" {
+			if err := creds.OverrideServerName(serverName); err != nil {
+				return creds, fmt.Errorf("Failed to override server name as %q due to: %s", serverName, err.Error())
+			}
+		}
+	}
+	return
+}
+
+func dial(ctx context.Context, address string, creds credentials.TransportCredentials, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	cc, err := grpcurl.BlockingDial(ctx, "tcp", address, creds, opts...)
+	if err != nil {
+		err = fmt.Errorf("Failed to dial target.host %q\n%s", address, err.Error())
+	}
+	return cc, err
+}
+
+// conn returns the connection, dialing it if needed. The caller uses it until
+// calling release, which may close a retired connection.
+func (s *Session) conn(ctx context.Context) (cc *grpc.ClientConn, release func(), err error) {
+	if err := s.cfg.Metadata.Validate(); err != nil {
+		return nil, nil, err
+	}
+	l := s.link
+	for {
+		l.mu.Lock()
+		if l.cc != nil && l.cc.GetState() == connectivity.Shutdown {
+			_ = l.closeLocked()
+		}
+		if l.cc != nil {
+			l.users++
+			cc := l.cc
+			l.mu.Unlock()
+			return cc, l.release, nil
+		}
+		dialing := l.dialing
+		if dialing == nil {
+			break
+		}
+		// wait for the other dial, and dial again if it failed
+		l.mu.Unlock()
+		select {
+		case <-dialing:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	dialing := make(chan struct{})
+	l.dialing = dialing
+	l.mu.Unlock()
+
+	cc, err = s.newConn(ctx)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.dialing = nil
+	close(dialing)
+	if err != nil {
+		if l.users == 0 {
+			l.retired = false
+		}
+		return nil, nil, err
+	}
+	l.cc = cc
+	l.users++
+	return cc, l.release, nil
+}
+
+func (s *Session) newConn(ctx context.Context) (*grpc.ClientConn, error) {
+	creds, err := generateCreds(s.cfg.PlainText, s.cfg.ServerName, &s.cfg.CA)
+	if err != nil {
+		return nil, err
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, s.cfg.DialTimeout)
+	defer cancel()
+	var opts []grpc.DialOption
+	if s.cfg.Authority != "" {
+		opts = append(opts, grpc.WithAuthority(s.cfg.Authority))
+	}
+	return dial(dialCtx, s.cfg.Address, creds, opts...)
+}
+
+// descSource returns the cached schema of the target, reflecting it from the
+// server or loading the configured files on first use. If the server cannot
+// be reached the schema last written to disk is used instead.
+func (s *Session) descSource(ctx context.Context) (grpcurl.DescriptorSource, error) {
+	if ds, ok := s.cache.Get(s.target()); ok {
+		return ds, nil
+	}
+	if s.cfg.Schema.Kind != SchemaReflection {
+		return s.loadFiles()
+	}
+	ds, err := s.reflect(ctx)
+	if err != nil {
+		if cached, lerr := s.cache.Load(s.cfg.Address); lerr == nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+	return ds, nil
+}
+
+func (s *Session) reflect(ctx context.Context) (grpcurl.DescriptorSource, error) {
+	cc, release, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	// the reflection stream carries the metadata of this session and lasts
+	// for the request only, cancelling ctx stops it
+	refClient := grpcreflect.NewClient(metadata.NewOutgoingContext(ctx, s.cfg.Metadata.outgoing()), rpb.NewServerReflectionClient(cc))
+	defer refClient.Reset()
+	files, err := grpcurl.GetAllFiles(grpcurl.DescriptorSourceFromServer(ctx, refClient))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("Failed to resolve schema due to:\n %s", err.Error())
+	}
+	return s.cache.Put(s.cfg.Address, files)
+}
+
+// RefreshSchema drops the cached schema of the target and reflects it again,
+// or reloads the proto/protoset files.
+func (s *Session) RefreshSchema(ctx context.Context) error {
+	s.cache.Invalidate(s.target())
+	_, err := s.descSource(ctx)
+	return err
+}
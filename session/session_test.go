@@ -0,0 +1,219 @@
+package session
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// testServer serves the given services only, or nothing but the http/2
+// handshake, enough to dial it.
+func testServer(t *testing.T, register ...func(*grpc.Server)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	for _, r := range register {
+		r(srv)
+	}
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestManagerSharesLinks(t *testing.T) {
+	base := Config{Address: "127.0.0.1:1", PlainText: true}
+	withMetadata := base
+	withMetadata.Metadata = Metadata{{Key: "x-user", Value: "me"}}
+	withSchema := base
+	withSchema.Schema = Schema{Kind: SchemaProtoFiles, Files: []string{"a.proto"}}
+	withAuthority := base
+	withAuthority.Authority = "other"
+	otherAddress := base
+	otherAddress.Address = "127.0.0.1:2"
+
+	tests := []struct {
+		name  string
+		cfg   Config
+		share bool
+	}{
+		{name: "same config", cfg: base, share: true},
+		{name: "other metadata", cfg: withMetadata, share: true},
+		{name: "other schema", cfg: withSchema, share: true},
+		{name: "other authority", cfg: withAuthority},
+		{name: "other address", cfg: otherAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(NewCache(""))
+			first := m.Get(base)
+			s := m.Get(tt.cfg)
+			if (s.link == first.link) != tt.share {
+				t.Errorf("link shared = %v, want %v", s.link == first.link, tt.share)
+			}
+			if len(s.Config().Metadata) != len(tt.cfg.Metadata) {
+				t.Errorf("session has metadata %v, want %v", s.Config().Metadata, tt.cfg.Metadata)
+			}
+		})
+	}
+}
+
+func TestLinkRetire(t *testing.T) {
+	addr := testServer(t)
+	ctx := context.Background()
+	s := New(Config{Address: addr, PlainText: true, DialTimeout: 5 * time.Second})
+
+	cc, release, err := s.conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc2, release2, err := s.conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc != cc2 {
+		t.Fatal("conn dialed twice")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if cc.GetState() == connectivity.Shutdown {
+		t.Fatal("connection closed while still in use")
+	}
+	release2()
+	if cc.GetState() != connectivity.Shutdown {
+		t.Fatal("connection not closed by its last user")
+	}
+	if _, ok := s.State(); ok {
+		t.Error("State() reports a closed connection")
+	}
+
+	// a closed session dials again
+	cc3, release3, err := s.conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release3()
+	if cc3 == cc {
+		t.Error("conn returned the closed connection")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStateDoesNotWaitOnDial(t *testing.T) {
+	// accepts connections but never answers the http/2 handshake
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			c, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	s := New(Config{Address: lis.Addr().String(), PlainText: true, DialTimeout: time.Second})
+	dialed := make(chan error, 1)
+	go func() {
+		_, _, err := s.conn(context.Background())
+		dialed <- err
+	}()
+	for {
+		s.link.mu.Lock()
+		dialing := s.link.dialing != nil
+		s.link.mu.Unlock()
+		if dialing {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	start := time.Now()
+	if _, ok := s.State(); ok {
+		t.Error("State() reports a connection while dialing")
+	}
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+	if waited := time.Since(start); waited > 100*time.Millisecond {
+		t.Errorf("State() and Close() waited %s on the dial", waited)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := s.conn(ctx); err != context.Canceled {
+		t.Errorf("conn() waiting on a dial error = %v, want %v", err, context.Canceled)
+	}
+	if err := <-dialed; err == nil {
+		t.Error("dial of a silent server succeeded")
+	}
+}
+
+// stuckReflection never answers, and reports the metadata and the end of
+// each reflection stream.
+type stuckReflection struct {
+	rpb.UnimplementedServerReflectionServer
+	md   chan metadata.MD
+	done chan struct{}
+}
+
+func (r *stuckReflection) ServerReflectionInfo(stream rpb.ServerReflection_ServerReflectionInfoServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	r.md <- md
+	<-stream.Context().Done()
+	r.done <- struct{}{}
+	return stream.Context().Err()
+}
+
+func TestReflectStopsWithContext(t *testing.T) {
+	r := &stuckReflection{md: make(chan metadata.MD, 1), done: make(chan struct{}, 1)}
+	addr := testServer(t, func(srv *grpc.Server) { rpb.RegisterServerReflectionServer(srv, r) })
+	s := New(Config{Address: addr, PlainText: true, Metadata: Metadata{{Key: "x-user", Value: "me"}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.reflect(ctx)
+		errc <- err
+	}()
+	select {
+	case md := <-r.md:
+		if got := md.Get("x-user"); len(got) != 1 || got[0] != "me" {
+			t.Errorf("reflection metadata x-user = %v, want [me]", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reflection stream not opened")
+	}
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("reflect() error = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-r.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reflection stream still open after cancelling")
+	}
+	// the connection is released with the stream
+	if s.link.users != 0 {
+		t.Errorf("connection still has %d users", s.link.users)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
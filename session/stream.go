@@ -0,0 +1,298 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ErrSendClosed = errors.New("Request stream is already half-closed")
+	ErrSendFull   = errors.New("Request stream is full, wait for the server to take the queued messages")
+)
+
+// StreamKind is the streaming type of a method.
+type StreamKind int
+
+const (
+	Unary StreamKind = iota
+	ServerStreaming
+	ClientStreaming
+	BidiStreaming
+)
+
+func (k StreamKind) String() string {
+	switch k {
+	case ServerStreaming:
+		return "server streaming"
+	case ClientStreaming:
+		return "client streaming"
+	case BidiStreaming:
+		return "bidi streaming"
+	default:
+		return "unary"
+	}
+}
+
+func (m Method) Kind() StreamKind {
+	switch {
+	case m.ClientStreaming && m.ServerStreaming:
+		return BidiStreaming
+	case m.ClientStreaming:
+		return ClientStreaming
+	case m.ServerStreaming:
+		return ServerStreaming
+	default:
+		return Unary
+	}
+}
+
+// ResolveMethod looks methodName ("service.method" or "service/method") up in
+// the schema of the target.
+func (s *Session) ResolveMethod(ctx context.Context, methodName string) (Method, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return Method{}, err
+	}
+	return resolveMethod(ds, methodName)
+}
+
+func resolveMethod(ds grpcurl.DescriptorSource, methodName string) (Method, error) {
+	name := methodName
+	if pos := strings.LastIndex(name, "/"); pos >= 0 {
+		name = name[:pos] + "." + name[pos+1:]
+	}
+	dsc, err := ds.FindSymbol(name)
+	if err != nil {
+		return Method{}, fmt.Errorf("Failed to resolve method %q due to %s", methodName, err.Error())
+	}
+	md, ok := dsc.(*desc.MethodDescriptor)
+	if !ok {
+		return Method{}, fmt.Errorf("Symbol %q is not a method", methodName)
+	}
+	return newMethod(md), nil
+}
+
+// StreamEvent is something that happened on a Stream. Every event but the
+// last one carries either response headers or a single response message.
+type StreamEvent struct {
+	Headers  metadata.MD
+	Response string // formatted as json
+
+	// set on the last event only
+	Done bool
+	Info CallInfo
+	Err  error // the call did not complete, Info is empty
+}
+
+// Stream is an RPC of any streaming type whose request messages are supplied
+// one by one and whose responses are delivered as they arrive.
+type Stream struct {
+	Method Method
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	ds     grpcurl.DescriptorSource
+	format grpcurl.Formatter
+	events chan StreamEvent
+
+	mu     sync.Mutex
+	reqs   chan string
+	closed bool
+}
+
+// OpenStream starts methodName. The call lasts until the server finishes it or
+// Cancel is called, ctx only bounds resolving the method and dialing.
+func (s *Session) OpenStream(ctx context.Context, methodName string) (*Stream, error) {
+	ds, err := s.descSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m, err := resolveMethod(ds, methodName)
+	if err != nil {
+		return nil, err
+	}
+	cc, release, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Stream{
+		Method: m,
+		ds:     ds,
+		format: grpcurl.NewJSONFormatter(true, grpcurl.AnyResolverFromDescriptorSource(ds)),
+		events: make(chan StreamEvent, 64),
+		reqs:   make(chan string, 64),
+	}
+	st.ctx, st.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		defer release()
+		h := NewRecorder(ds, &streamHandler{st: st})
+		err := grpcurl.InvokeRPC(st.ctx, ds, cc, m.Name, s.cfg.Metadata.headers(), h, st.next)
+		last := StreamEvent{Done: true, Info: h.Info}
+		if err != nil {
+			last.Info = CallInfo{}
+			last.Err = fmt.Errorf("Error invoking method %s due to: %s", m.Name, err.Error())
+		}
+		select {
+		case st.events <- last:
+		case <-st.ctx.Done():
+			// cancelled, the consumer may be gone: deliver only if there is room
+			select {
+			case st.events <- last:
+			default:
+			}
+		}
+		close(st.events)
+		st.cancel()
+	}()
+	return st, nil
+}
+
+// Events delivers the headers, responses and final status of the call, and
+// is closed after the event with Done set.
+func (st *Stream) Events() <-chan StreamEvent {
+	return st.events
+}
+
+// Send queues the json messages in text, either a single message or several
+// concatenated ones, and returns how many were queued. It does not block: the
+// messages are queued all at once or, with ErrSendFull, not at all. Methods
+// without client streaming are half-closed after their only message.
+func (st *Stream) Send(text string) (int, error) {
+	msgs, err := st.split(text)
+	if err != nil {
+		return 0, err
+	}
+	if !st.Method.ClientStreaming && len(msgs) != 1 {
+		return 0, fmt.Errorf("Method %s is %s and takes exactly one message, got %d", st.Method.Name, st.Method.Kind(), len(msgs))
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.closed {
+		return 0, ErrSendClosed
+	}
+	if err := st.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(msgs) > cap(st.reqs) {
+		return 0, fmt.Errorf("Request stream takes at most %d messages at once, got %d", cap(st.reqs), len(msgs))
+	}
+	// only Send adds to reqs, so the room left cannot shrink meanwhile
+	if len(msgs) > cap(st.reqs)-len(st.reqs) {
+		return 0, ErrSendFull
+	}
+	for _, msg := range msgs {
+		st.reqs <- msg
+	}
+	if !st.Method.ClientStreaming {
+		st.closeLocked()
+	}
+	return len(msgs), nil
+}
+
+// CloseSend half-closes the call: the server sees the end of the request
+// stream while responses keep being delivered.
+func (st *Stream) CloseSend() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.closeLocked()
+}
+
+func (st *Stream) closeLocked() {
+	if !st.closed {
+		close(st.reqs)
+		st.closed = true
+	}
+}
+
+// Cancel aborts the call.
+func (st *Stream) Cancel() {
+	st.cancel()
+}
+
+// split checks every json message of text against the request type and
+// returns them one by one.
+func (st *Stream) split(text string) ([]string, error) {
+	var msgs []string
+	dec := json.NewDecoder(strings.NewReader(text))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Failed to parse request message %d due to: %s", len(msgs)+1, err.Error())
+		}
+		dm := dynamic.NewMessage(st.Method.Descriptor.GetInputType())
+		if err := st.unmarshal(string(raw), dm); err != nil {
+			return nil, fmt.Errorf("Failed to parse request message %d due to: %s", len(msgs)+1, err.Error())
+		}
+		msgs = append(msgs, string(raw))
+	}
+	if len(msgs) == 0 {
+		return nil, errors.New("No request message given")
+	}
+	return msgs, nil
+}
+
+func (st *Stream) unmarshal(msg string, m proto.Message) error {
+	u := jsonpb.Unmarshaler{AnyResolver: grpcurl.AnyResolverFromDescriptorSource(st.ds)}
+	return u.Unmarshal(strings.NewReader(msg), m)
+}
+
+// next is the grpcurl.RequestSupplier of the call.
+func (st *Stream) next(m proto.Message) error {
+	select {
+	case msg, ok := <-st.reqs:
+		if !ok {
+			return io.EOF
+		}
+		return st.unmarshal(msg, m)
+	case <-st.ctx.Done():
+		return st.ctx.Err()
+	}
+}
+
+func (st *Stream) emit(ev StreamEvent) {
+	select {
+	case st.events <- ev:
+	case <-st.ctx.Done():
+	}
+}
+
+// streamHandler is the grpcurl.InvocationEventHandler of a Stream.
+type streamHandler struct {
+	st *Stream
+}
+
+func (h *streamHandler) OnResolveMethod(*desc.MethodDescriptor) {}
+
+func (h *streamHandler) OnSendHeaders(metadata.MD) {}
+
+func (h *streamHandler) OnReceiveHeaders(md metadata.MD) {
+	h.st.emit(StreamEvent{Headers: md})
+}
+
+func (h *streamHandler) OnReceiveResponse(resp proto.Message) {
+	txt, err := h.st.format(resp)
+	if err != nil {
+		txt = fmt.Sprintf("Failed to format response message due to: %s", err.Error())
+	}
+	h.st.emit(StreamEvent{Response: txt})
+}
+
+func (h *streamHandler) OnReceiveTrailers(*status.Status, metadata.MD) {}
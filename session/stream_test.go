@@ -0,0 +1,139 @@
+package session
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// testStream is a Stream of methodName of the test schema which is not
+// connected, its requests are taken with next.
+func testStream(t *testing.T, methodName string, queue int) *Stream {
+	t.Helper()
+	ds, err := grpcurl.DescriptorSourceFromFileDescriptors(testFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := resolveMethod(ds, methodName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := &Stream{Method: m, ds: ds, reqs: make(chan string, queue)}
+	st.ctx, st.cancel = context.WithCancel(context.Background())
+	t.Cleanup(st.cancel)
+	return st
+}
+
+// received takes the queued requests of st until it is half-closed, and
+// returns their names.
+func received(t *testing.T, st *Stream) []string {
+	t.Helper()
+	var names []string
+	for {
+		dm := dynamic.NewMessage(st.Method.Descriptor.GetInputType())
+		if err := st.next(dm); err == io.EOF {
+			return names
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, dm.GetFieldByName("name").(string))
+	}
+}
+
+func TestStreamSend(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		queue   int
+		sends   []string
+		wantN   []int
+		wantErr []string
+	}{
+		{
+			name:    "unary takes one message and half-closes",
+			method:  "test.Tester/Do",
+			queue:   4,
+			sends:   []string{`{"name":"a"}{"name":"b"}`, `{"name":"a"}`, `{"name":"c"}`},
+			wantN:   []int{0, 1, 0},
+			wantErr: []string{"exactly one message", "", ErrSendClosed.Error()},
+		},
+		{
+			name:    "client streaming queues concatenated messages",
+			method:  "test.Tester.Upload",
+			queue:   4,
+			sends:   []string{`{"name":"a"} {"name":"b"}`, `{"name":"c"}`},
+			wantN:   []int{2, 1},
+			wantErr: []string{"", ""},
+		},
+		{
+			name:    "full queue takes nothing",
+			method:  "test.Tester.Upload",
+			queue:   2,
+			sends:   []string{`{"name":"a"}`, `{"name":"b"}{"name":"c"}`, `{"name":"b"}`, `{"name":"d"}`},
+			wantN:   []int{1, 0, 1, 0},
+			wantErr: []string{"", ErrSendFull.Error(), "", ErrSendFull.Error()},
+		},
+		{
+			name:    "more than the queue holds",
+			method:  "test.Tester.Upload",
+			queue:   2,
+			sends:   []string{`{"name":"a"}{"name":"b"}{"name":"c"}`},
+			wantN:   []int{0},
+			wantErr: []string{"at most 2 messages"},
+		},
+		{
+			name:    "invalid messages",
+			method:  "test.Tester.Upload",
+			queue:   2,
+			sends:   []string{``, `{"name":`, `{"missing":1}`, `{"id":"x"}`},
+			wantN:   []int{0, 0, 0, 0},
+			wantErr: []string{"No request message", "message 1", "message 1", "message 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := testStream(t, tt.method, tt.queue)
+			for i, text := range tt.sends {
+				n, err := st.Send(text)
+				if n != tt.wantN[i] {
+					t.Errorf("Send(%q) = %d, want %d", text, n, tt.wantN[i])
+				}
+				if tt.wantErr[i] == "" && err != nil {
+					t.Errorf("Send(%q) error = %v", text, err)
+				} else if tt.wantErr[i] != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr[i])) {
+					t.Errorf("Send(%q) error = %v, want it to contain %q", text, err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStreamCloseSend(t *testing.T) {
+	st := testStream(t, "test.Tester.Upload", 4)
+	if _, err := st.Send(`{"name":"a"}{"name":"b"}`); err != nil {
+		t.Fatal(err)
+	}
+	st.CloseSend()
+	st.CloseSend()
+	if _, err := st.Send(`{"name":"c"}`); err != ErrSendClosed {
+		t.Errorf("Send() after CloseSend error = %v, want %v", err, ErrSendClosed)
+	}
+	if got := received(t, st); strings.Join(got, ",") != "a,b" {
+		t.Errorf("received %v, want [a b]", got)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	st := testStream(t, "test.Tester.Upload", 4)
+	st.Cancel()
+	if _, err := st.Send(`{"name":"a"}`); err != context.Canceled {
+		t.Errorf("Send() after Cancel error = %v, want %v", err, context.Canceled)
+	}
+	if err := st.next(dynamic.NewMessage(st.Method.Descriptor.GetInputType())); err != context.Canceled {
+		t.Errorf("next() after Cancel error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+)
+
+// JSONObject is a json object keeping the order of its members, e.g. the
+// order of the fields of a message.
+type JSONObject []JSONMember
+
+type JSONMember struct {
+	Key   string
+	Value interface{}
+}
+
+func (o JSONObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(m.Key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MessageTemplate returns a json skeleton of md with every field set to its
+// default value. Nested messages are filled in, repeated fields and maps get
+// one entry and oneofs their first member only. A message nested in itself is
+// left empty.
+func MessageTemplate(md *desc.MessageDescriptor) string {
+	b, _ := json.MarshalIndent(messageTemplate(md, map[string]bool{}), "", "  ")
+	return string(b)
+}
+
+func messageTemplate(md *desc.MessageDescriptor, path map[string]bool) interface{} {
+	if v, ok := wellKnownTemplate(md); ok {
+		return v
+	}
+	name := md.GetFullyQualifiedName()
+	if path[name] {
+		return JSONObject{}
+	}
+	path[name] = true
+	defer delete(path, name)
+
+	res := JSONObject{}
+	oneofs := map[string]bool{}
+	for _, fd := range md.GetFields() {
+		if oo := fd.GetOneOf(); oo != nil && !oo.IsSynthetic() {
+			if oneofs[oo.GetName()] {
+				continue
+			}
+			oneofs[oo.GetName()] = true
+		}
+		res = append(res, JSONMember{Key: fd.GetJSONName(), Value: fieldTemplate(fd, path)})
+	}
+	return res
+}
+
+func fieldTemplate(fd *desc.FieldDescriptor, path map[string]bool) interface{} {
+	if fd.IsMap() {
+		key := "key"
+		switch fd.GetMapKeyType().GetType() {
+		case descpb.FieldDescriptorProto_TYPE_STRING:
+		case descpb.FieldDescriptorProto_TYPE_BOOL:
+			key = "false"
+		default:
+			key = "0"
+		}
+		return JSONObject{{Key: key, Value: valueTemplate(fd.GetMapValueType(), path)}}
+	}
+	v := valueTemplate(fd, path)
+	if fd.IsRepeated() {
+		return []interface{}{v}
+	}
+	return v
+}
+
+// valueTemplate is the default of a single value of fd, in its json form.
+func valueTemplate(fd *desc.FieldDescriptor, path map[string]bool) interface{} {
+	switch fd.GetType() {
+	case descpb.FieldDescriptorProto_TYPE_MESSAGE, descpb.FieldDescriptorProto_TYPE_GROUP:
+		return messageTemplate(fd.GetMessageType(), path)
+	case descpb.FieldDescriptorProto_TYPE_ENUM:
+		return fd.GetEnumType().GetValues()[0].GetName()
+	case descpb.FieldDescriptorProto_TYPE_BOOL:
+		return false
+	case descpb.FieldDescriptorProto_TYPE_STRING, descpb.FieldDescriptorProto_TYPE_BYTES:
+		return ""
+	case descpb.FieldDescriptorProto_TYPE_INT64, descpb.FieldDescriptorProto_TYPE_SINT64,
+		descpb.FieldDescriptorProto_TYPE_SFIXED64, descpb.FieldDescriptorProto_TYPE_UINT64,
+		descpb.FieldDescriptorProto_TYPE_FIXED64:
+		// 64 bit integers are strings in json
+		return "0"
+	default:
+		return 0
+	}
+}
+
+// wellKnownTemplate covers the google.protobuf types with a json form of
+// their own.
+func wellKnownTemplate(md *desc.MessageDescriptor) (interface{}, bool) {
+	switch md.GetFullyQualifiedName() {
+	case "google.protobuf.Timestamp":
+		return "1970-01-01T00:00:00Z", true
+	case "google.protobuf.Duration":
+		return "0s", true
+	case "google.protobuf.FieldMask":
+		return "", true
+	case "google.protobuf.Struct", "google.protobuf.Empty":
+		return JSONObject{}, true
+	case "google.protobuf.Value":
+		return nil, true
+	case "google.protobuf.ListValue":
+		return []interface{}{}, true
+	case "google.protobuf.Any":
+		// a placeholder to replace, an Any without a type does not parse
+		return JSONObject{
+			{Key: "@type", Value: "type.googleapis.com/google.protobuf.Empty"},
+			{Key: "value", Value: JSONObject{}},
+		}, true
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return valueTemplate(md.FindFieldByName("value"), nil), true
+	}
+	return nil, false
+}
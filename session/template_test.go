@@ -0,0 +1,49 @@
+package session
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{message: "test.Node", want: `{"name":"","children":[{}]}`},
+		{message: "test.Reply", want: `{"nodes":[{"name":"","children":[{}]}]}`},
+		{
+			message: "test.Request",
+			want: `{"id":0,"big":"0","flag":false,"name":"","color":"RED","tags":[""],"counts":{"key":0},` +
+				`"text":"","at":"1970-01-01T00:00:00Z","every":"0s","limit":"0",` +
+				`"extra":{"@type":"type.googleapis.com/google.protobuf.Empty","value":{}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			md := testMessage(t, tt.message)
+			got := MessageTemplate(md)
+			if err := jsonpb.UnmarshalString(got, dynamic.NewMessage(md)); err != nil {
+				t.Fatalf("MessageTemplate() does not parse as %s: %v\n%s", tt.message, err, got)
+			}
+			compact, _ := json.Marshal(messageTemplate(md, map[string]bool{}))
+			if string(compact) != tt.want {
+				t.Errorf("MessageTemplate() = %s, want %s", compact, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONObjectKeepsOrder(t *testing.T) {
+	o := JSONObject{{Key: "b", Value: 1}, {Key: "a", Value: JSONObject{}}, {Key: "c", Value: []interface{}{"x"}}}
+	got, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":1,"a":{},"c":["x"]}`; string(got) != want {
+		t.Errorf("Marshal() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// Validate checks the PEM files exist and hold what they are meant to: CA
+// certificates, and a client certificate matching its private key.
+func (ca *CA) Validate() error {
+	if ca.CACert != "" {
+		if err := validateCACert(ca.CACert); err != nil {
+			return err
+		}
+	}
+	if (ca.Cert == "") != (ca.Key == "") {
+		return errors.New("Client certificate and client key must be given together")
+	}
+	if ca.Cert != "" {
+		if _, err := tls.LoadX509KeyPair(ca.Cert, ca.Key); err != nil {
+			return fmt.Errorf("Failed to load client certificate %q and key %q due to: %s", ca.Cert, ca.Key, err.Error())
+		}
+	}
+	return nil
+}
+
+func validateCACert(file string) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("Failed to read root CA %q due to: %s", file, err.Error())
+	}
+	n := 0
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("Failed to parse certificate %d of root CA %q due to: %s", n+1, file, err.Error())
+		}
+		n++
+	}
+	if n == 0 {
+		return fmt.Errorf("Root CA %q does not contain any PEM certificate", file)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// testPKI is a root CA with a server certificate for example.test and a
+// client certificate, all written as PEM files.
+type testPKI struct {
+	CACert, ServerCert, ServerKey, ClientCert, ClientKey string
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, typ string, der []byte) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	newKey := func() (*ecdsa.PrivateKey, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key, der
+	}
+	now := time.Now()
+	caKey, _ := newKey()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := func(serial int64, name string, usage x509.ExtKeyUsage) (cert, key string) {
+		k, der := newKey()
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    now.Add(-time.Hour),
+			NotAfter:     now.Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		certDER, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &k.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return write(name+".crt", "CERTIFICATE", certDER), write(name+".key", "EC PRIVATE KEY", der)
+	}
+	pki := testPKI{CACert: write("ca.crt", "CERTIFICATE", caDER)}
+	pki.ServerCert, pki.ServerKey = leaf(2, "example.test", x509.ExtKeyUsageServerAuth)
+	pki.ClientCert, pki.ClientKey = leaf(3, "client.test", x509.ExtKeyUsageClientAuth)
+	return pki
+}
+
+// tlsServer serves health and reflection over TLS, requiring a client
+// certificate signed by the CA of pki.
+func tlsServer(t *testing.T, pki testPKI) string {
+	t.Helper()
+	cert, err := tls.LoadX509KeyPair(pki.ServerCert, pki.ServerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(pki.CACert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(b)
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.Creds(creds))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestCAValidate(t *testing.T) {
+	pki := newTestPKI(t)
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.crt")
+	if err := ioutil.WriteFile(corrupt, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("nope")}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ca      CA
+		wantErr string
+	}{
+		{name: "nothing"},
+		{name: "root CA only", ca: CA{CACert: pki.CACert}},
+		{name: "client certificate", ca: CA{CACert: pki.CACert, Cert: pki.ClientCert, Key: pki.ClientKey}},
+		{name: "missing root CA", ca: CA{CACert: filepath.Join(dir, "missing.crt")}, wantErr: "Failed to read root CA"},
+		{name: "key as root CA", ca: CA{CACert: pki.ClientKey}, wantErr: "does not contain any PEM certificate"},
+		{name: "corrupt root CA", ca: CA{CACert: corrupt}, wantErr: "Failed to parse certificate 1"},
+		{name: "certificate without key", ca: CA{Cert: pki.ClientCert}, wantErr: "must be given together"},
+		{name: "key without certificate", ca: CA{Key: pki.ClientKey}, wantErr: "must be given together"},
+		{name: "key of another certificate", ca: CA{Cert: pki.ClientCert, Key: pki.ServerKey}, wantErr: "Failed to load client certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.ca.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSConnection(t *testing.T) {
+	pki := newTestPKI(t)
+	addr := tlsServer(t, pki)
+	client := CA{CACert: pki.CACert, Cert: pki.ClientCert, Key: pki.ClientKey}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		ok   bool
+	}{
+		{name: "verified with client certificate", cfg: Config{CA: client, ServerName: "example.test"}, ok: true},
+		{name: "skip verify", cfg: Config{CA: CA{Insecure: true, Cert: pki.ClientCert, Key: pki.ClientKey}}, ok: true},
+		{name: "wrong server name", cfg: Config{CA: client, ServerName: "other.test"}},
+		{name: "unknown root CA", cfg: Config{CA: CA{Cert: pki.ClientCert, Key: pki.ClientKey}, ServerName: "example.test"}},
+		{name: "no client certificate", cfg: Config{CA: CA{CACert: pki.CACert}, ServerName: "example.test"}},
+		{name: "plaintext", cfg: Config{PlainText: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Address = addr
+			cfg.DialTimeout = time.Second
+			s := New(cfg)
+			defer s.Close()
+			_, err := s.ListServices(context.Background())
+			if (err == nil) != tt.ok {
+				t.Errorf("ListServices() error = %v, want success %v", err, tt.ok)
+			}
+		})
+	}
+}
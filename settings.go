@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/RoomOfRequirement/qt_grpc/session"
+)
+
+// settings are kept between sessions in the user config directory.
+type settings struct {
+	LoadTest   session.LoadTestOptions `json:"load_test"`
+	Assertions []string                `json:"assertions"` // checked against every report
+}
+
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "qt_grpc", "settings.json"), nil
+}
+
+// loadSettings returns the defaults when nothing was saved yet.
+func loadSettings() (settings, error) {
+	st := settings{LoadTest: session.DefaultLoadTestOptions()}
+	path, err := settingsPath()
+	if err != nil {
+		return st, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return st, nil
+	} else if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal(b, &st)
+	return st, err
+}
+
+func (st settings) save() error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
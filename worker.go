@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/therecipe/qt/core"
+)
+
+// worker runs network operations off the GUI thread. Qt widgets must only be
+// touched from the GUI thread, so each operation returns a closure that is
+// queued and run by a timer there.
+type worker struct {
+	timer   *core.QTimer
+	results chan func()
+
+	mu      sync.Mutex
+	cancels map[int]context.CancelFunc
+	next    int
+
+	// called on the GUI thread whenever the worker becomes busy or idle
+	onBusy func(busy bool)
+}
+
+func newWorker(parent core.QObject_ITF, onBusy func(busy bool)) *worker {
+	w := &worker{
+		timer:   core.NewQTimer(parent),
+		results: make(chan func(), 16),
+		cancels: map[int]context.CancelFunc{},
+		onBusy:  onBusy,
+	}
+	w.timer.ConnectTimeout(w.drain)
+	return w
+}
+
+// Go runs work in a goroutine, the closure it returns is run on the GUI
+// thread once it is done.
+func (w *worker) Go(work func(ctx context.Context) func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w.mu.Lock()
+	id := w.next
+	w.next++
+	w.cancels[id] = cancel
+	busy := len(w.cancels) == 1
+	w.mu.Unlock()
+
+	if busy {
+		w.onBusy(true)
+		w.timer.Start(30)
+	}
+
+	go func() {
+		done := work(ctx)
+		cancel()
+		// queued before leaving cancels, so drain never sees the worker idle
+		// while a result is still on its way
+		w.results <- done
+		w.mu.Lock()
+		delete(w.cancels, id)
+		w.mu.Unlock()
+	}()
+}
+
+// Cancel cancels the context of every running operation.
+func (w *worker) Cancel() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, cancel := range w.cancels {
+		cancel()
+	}
+}
+
+func (w *worker) drain() {
+	for {
+		select {
+		case done := <-w.results:
+			if done != nil {
+				done()
+			}
+		default:
+			w.mu.Lock()
+			idle := len(w.cancels) == 0 && len(w.results) == 0
+			w.mu.Unlock()
+			if idle {
+				w.timer.Stop()
+				w.onBusy(false)
+			}
+			return
+		}
+	}
+}